@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -15,6 +16,7 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v2"
+	"k8s.io/client-go/kubernetes"
 )
 
 var (
@@ -74,7 +76,40 @@ func main() {
 	metrics.Init(*addr)
 	metricsStore := metrics.NewMetricsStore(cfg.MetricsNamePrefix)
 
-	engine := exporter.NewEngine(&cfg, &exporter.ChannelBasedReceiverRegistry{MetricsStore: metricsStore})
+	engine := exporter.NewEngine(&cfg, &exporter.ChannelBasedReceiverRegistry{MetricsStore: metricsStore}, metricsStore)
+	var silences *exporter.SilenceStore
+	if cfg.Silences.Enabled {
+		var persister exporter.SilencePersister
+		if cfg.Silences.ConfigMap != "" {
+			client, err := kubernetes.NewForConfig(kubeconfig)
+			if err != nil {
+				log.Fatal().Err(err).Msg("cannot create kubernetes client for silences")
+			}
+			persister = kube.NewConfigMapStore(client, cfg.Silences.Namespace, cfg.Silences.ConfigMap, "silences.json")
+		}
+
+		silences = exporter.NewSilenceStore(persister)
+		if err := silences.Load(context.Background()); err != nil {
+			log.Fatal().Err(err).Msg("cannot load silences")
+		}
+		engine.SetSilences(silences)
+
+		mux := http.DefaultServeMux
+		if cfg.Silences.Address != "" {
+			mux = http.NewServeMux()
+		}
+		if cfg.Silences.BearerToken == "" {
+			log.Warn().Msg("silences API does not require a bearer token")
+		}
+		handler := exporter.RequireBearerToken(cfg.Silences.BearerToken, silences)
+		mux.Handle(exporter.SilencesAPIPath, handler)
+		mux.Handle(exporter.SilencesAPIPath+"/", handler)
+		if cfg.Silences.Address != "" {
+			go func() {
+				log.Fatal().Err(http.ListenAndServe(cfg.Silences.Address, mux)).Msg("cannot serve silences API")
+			}()
+		}
+	}
 	onEvent := engine.OnEvent
 	if len(cfg.ClusterName) != 0 {
 		onEvent = func(event *kube.EnhancedEvent) {
@@ -87,6 +122,10 @@ func main() {
 	w := kube.NewEventWatcher(kubeconfig, cfg.Namespace, cfg.MaxEventAgeSeconds, metricsStore, onEvent)
 
 	ctx, cancel := context.WithCancel(context.Background())
+	if silences != nil {
+		// Every replica reloads the silences, so the ones created through another replica apply to the leader
+		go silences.Reload(ctx, time.Duration(cfg.Silences.ReloadIntervalSeconds)*time.Second)
+	}
 	leaderLost := make(chan bool)
 	if cfg.LeaderElection.Enabled {
 		l, err := kube.NewLeaderElector(cfg.LeaderElection.LeaderElectionID, kubeconfig,
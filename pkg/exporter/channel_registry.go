@@ -2,7 +2,9 @@ package exporter
 
 import (
 	"context"
+	"errors"
 	"sync"
+	"text/template"
 
 	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
 	"github.com/resmoio/kubernetes-event-exporter/pkg/metrics"
@@ -59,7 +61,13 @@ func (r *ChannelBasedReceiverRegistry) Register(name string, receiver sinks.Sink
 				err := receiver.Send(context.Background(), &ev)
 				if err != nil {
 					r.MetricsStore.SendErrors.Inc()
-					log.Debug().Err(err).Str("sink", name).Str("event", ev.Message).Msg("Cannot send event")
+					// Broken templates are a config problem and would go unnoticed at debug level
+					var execErr template.ExecError
+					if errors.As(err, &execErr) {
+						log.Error().Err(err).Str("sink", name).Str("event", ev.Message).Msg("Cannot render template")
+					} else {
+						log.Debug().Err(err).Str("sink", name).Str("event", ev.Message).Msg("Cannot send event")
+					}
 				}
 			case <-exitCh:
 				log.Info().Str("sink", name).Msg("Closing the sink")
@@ -2,6 +2,7 @@ package exporter
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
 	"strconv"
 
@@ -15,18 +16,28 @@ type Config struct {
 	// Route is the top route that the events will match
 	// TODO: There is currently a tight coupling with route and config, but not with receiver config and sink so
 	// TODO: I am not sure what to do here.
-	LogLevel           string                    `yaml:"logLevel"`
-	LogFormat          string                    `yaml:"logFormat"`
-	ThrottlePeriod     int64                     `yaml:"throttlePeriod"`
-	MaxEventAgeSeconds int64                     `yaml:"maxEventAgeSeconds"`
-	ClusterName        string                    `yaml:"clusterName,omitempty"`
-	Namespace          string                    `yaml:"namespace"`
-	LeaderElection     kube.LeaderElectionConfig `yaml:"leaderElection"`
-	Route              Route                     `yaml:"route"`
-	Receivers          []sinks.ReceiverConfig    `yaml:"receivers"`
-	KubeQPS            float32                   `yaml:"kubeQPS,omitempty"`
-	KubeBurst          int                       `yaml:"kubeBurst,omitempty"`
-	MetricsNamePrefix  string					 `yaml:"metricsNamePrefix,omitempty"`
+	LogLevel           string                            `yaml:"logLevel"`
+	LogFormat          string                            `yaml:"logFormat"`
+	ThrottlePeriod     int64                             `yaml:"throttlePeriod"`
+	MaxEventAgeSeconds int64                             `yaml:"maxEventAgeSeconds"`
+	ClusterName        string                            `yaml:"clusterName,omitempty"`
+	Namespace          string                            `yaml:"namespace"`
+	LeaderElection     kube.LeaderElectionConfig         `yaml:"leaderElection"`
+	Route              Route                             `yaml:"route"`
+	Severity           SeverityConfig                    `yaml:"severity"`
+	DefaultReceiver    string                            `yaml:"defaultReceiver,omitempty"`
+	TimeIntervals      []TimeInterval                    `yaml:"timeIntervals,omitempty"`
+	Silences           SilencesConfig                    `yaml:"silences"`
+	TemplateMissingKey string                            `yaml:"templateMissingKey,omitempty"`
+	ConsoleURL         string                            `yaml:"consoleURL,omitempty"`
+	Templates          map[string]string                 `yaml:"templates,omitempty"`
+	TemplateFiles      []string                          `yaml:"templateFiles,omitempty"`
+	Layouts            map[string]map[string]interface{} `yaml:"layouts,omitempty"`
+	Redaction          *sinks.RedactionConfig            `yaml:"redaction,omitempty"`
+	Receivers          []sinks.ReceiverConfig            `yaml:"receivers"`
+	KubeQPS            float32                           `yaml:"kubeQPS,omitempty"`
+	KubeBurst          int                               `yaml:"kubeBurst,omitempty"`
+	MetricsNamePrefix  string                            `yaml:"metricsNamePrefix,omitempty"`
 }
 
 func (c *Config) Validate() error {
@@ -36,10 +47,33 @@ func (c *Config) Validate() error {
 	if err := c.validateMetricsNamePrefix(); err != nil {
 		return err
 	}
+	if err := c.validateTemplates(); err != nil {
+		return err
+	}
+	intervals, err := c.validateTimeIntervals()
+	if err != nil {
+		return err
+	}
+	if err := c.Severity.Validate(intervals); err != nil {
+		log.Error().Err(err).Msg("config.severity is invalid")
+		return fmt.Errorf("validateSeverity failed: %w", err)
+	}
+	if err := c.Route.Validate(intervals); err != nil {
+		log.Error().Err(err).Msg("config.route is invalid")
+		return fmt.Errorf("validateRoute failed: %w", err)
+	}
+	if err := c.validateDefaultReceiver(); err != nil {
+		return err
+	}
+	if err := c.Silences.Validate(); err != nil {
+		log.Error().Err(err).Msg("config.silences is invalid")
+		return fmt.Errorf("validateSilences failed: %w", err)
+	}
+	if err := c.validateReceivers(); err != nil {
+		return err
+	}
 
 	// No duplicate receivers
-	// Receivers individually
-	// Routers recursive
 	return nil
 }
 
@@ -69,6 +103,68 @@ func (c *Config) validateMaxEventAgeSeconds() error {
 	return nil
 }
 
+func (c *Config) validateTimeIntervals() (map[string]*TimeInterval, error) {
+	intervals := make(map[string]*TimeInterval, len(c.TimeIntervals))
+	for i := range c.TimeIntervals {
+		ti := &c.TimeIntervals[i]
+		if err := ti.Validate(); err != nil {
+			log.Error().Err(err).Msg("config.timeIntervals is invalid")
+			return nil, fmt.Errorf("validateTimeIntervals failed: %w", err)
+		}
+		if _, ok := intervals[ti.Name]; ok {
+			log.Error().Str("name", ti.Name).Msg("config.timeIntervals has duplicate names")
+			return nil, errors.New("validateTimeIntervals failed")
+		}
+		intervals[ti.Name] = ti
+	}
+	return intervals, nil
+}
+
+func (c *Config) validateDefaultReceiver() error {
+	if c.DefaultReceiver == "" {
+		return nil
+	}
+	for _, r := range c.Receivers {
+		if r.Name == c.DefaultReceiver {
+			return nil
+		}
+	}
+	log.Error().Str("receiver", c.DefaultReceiver).Msg("config.defaultReceiver does not match any receiver")
+	return errors.New("validateDefaultReceiver failed")
+}
+
+// validateTemplates sets up what the templates of the route and the receivers use, so it runs before both
+func (c *Config) validateTemplates() error {
+	if err := sinks.SetTemplateMissingKey(c.TemplateMissingKey); err != nil {
+		log.Error().Err(err).Msg("config.templateMissingKey is invalid")
+		return fmt.Errorf("validateTemplates failed: %w", err)
+	}
+	sinks.SetConsoleURL(c.ConsoleURL)
+	if err := sinks.SetNamedTemplates(c.Templates, c.TemplateFiles); err != nil {
+		log.Error().Err(err).Msg("config.templates is invalid")
+		return fmt.Errorf("validateTemplates failed: %w", err)
+	}
+	sinks.SetLayouts(c.Layouts)
+	return nil
+}
+
+func (c *Config) validateReceivers() error {
+	if c.Redaction != nil {
+		if err := c.Redaction.Validate(); err != nil {
+			log.Error().Err(err).Msg("config.redaction is invalid")
+			return fmt.Errorf("validateReceivers failed: %w", err)
+		}
+	}
+
+	for i := range c.Receivers {
+		if err := c.Receivers[i].Validate(); err != nil {
+			log.Error().Err(err).Str("receiver", c.Receivers[i].Name).Msg("config.receivers is invalid")
+			return fmt.Errorf("validateReceivers failed: receiver %s: %w", c.Receivers[i].Name, err)
+		}
+	}
+	return nil
+}
+
 func (c *Config) validateMetricsNamePrefix() error {
 	if c.MetricsNamePrefix != "" {
 		// https://prometheus.io/docs/concepts/data_model/#metric-names-and-labels
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/resmoio/kubernetes-event-exporter/pkg/sinks"
 	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
@@ -149,3 +150,141 @@ func TestValidate_MetricsNamePrefix_WhenInvalid(t *testing.T) {
 		assert.Contains(t, output.String(), "config.metricsNamePrefix should match the regex: ^[a-zA-Z][a-zA-Z0-9_:]*_$")
 	}
 }
+
+func TestValidate_RouteExpr(t *testing.T) {
+	const yml = `
+route:
+  routes:
+    - drop:
+        - expr: "event.reason.startsWith('Failed')"
+      match:
+        - receiver: stdout
+          expr: "event.count >"
+receivers:
+  - name: stdout
+    stdout: {}
+`
+
+	cfg := readConfig(t, yml)
+	assert.Equal(t, "event.reason.startsWith('Failed')", cfg.Route.Routes[0].Drop[0].Expr)
+
+	err := cfg.Validate()
+	assert.Error(t, err)
+
+	cfg.Route.Routes[0].Match[0].Expr = "event.count > 3"
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_DefaultReceiver(t *testing.T) {
+	config := Config{
+		DefaultReceiver: "missing",
+		Receivers: []sinks.ReceiverConfig{{
+			Name:   "stdout",
+			Stdout: &sinks.StdoutConfig{},
+		}},
+	}
+	assert.Error(t, config.Validate())
+
+	config.DefaultReceiver = "stdout"
+	assert.NoError(t, config.Validate())
+}
+
+func TestValidate_TimeIntervals(t *testing.T) {
+	const yml = `
+timeIntervals:
+  - name: business-hours
+    location: Europe/Berlin
+    weekdays: ["monday:friday"]
+    times:
+      - start: "09:00"
+        end: "17:00"
+route:
+  routes:
+    - match:
+        - receiver: stdout
+          activeTimeIntervals: [business-hours]
+      muteTimeIntervals: [business-hours]
+receivers:
+  - name: stdout
+    stdout: {}
+`
+
+	cfg := readConfig(t, yml)
+	assert.Equal(t, "Europe/Berlin", cfg.TimeIntervals[0].Location)
+	assert.Equal(t, []string{"business-hours"}, cfg.Route.Routes[0].Match[0].ActiveTimeIntervals)
+	assert.Equal(t, []string{"business-hours"}, cfg.Route.Routes[0].MuteTimeIntervals)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Route.Routes[0].MuteTimeIntervals = []string{"missing"}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_ReceiverTemplates(t *testing.T) {
+	config := Config{
+		Receivers: []sinks.ReceiverConfig{{
+			Name:   "stdout",
+			Stdout: &sinks.StdoutConfig{Layout: map[string]interface{}{"message": "{{ .Message"}},
+		}},
+	}
+	assert.Error(t, config.Validate())
+
+	config.Receivers[0].Stdout.Layout["message"] = "{{ .Message }}"
+	assert.NoError(t, config.Validate())
+
+	config.TemplateMissingKey = "nope"
+	assert.Error(t, config.Validate())
+	config.TemplateMissingKey = ""
+	assert.NoError(t, config.Validate())
+}
+
+func TestValidate_NamedTemplates(t *testing.T) {
+	config := Config{
+		Templates: map[string]string{"title": "{{ .Reason }}"},
+		Receivers: []sinks.ReceiverConfig{{
+			Name:   "stdout",
+			Stdout: &sinks.StdoutConfig{Layout: map[string]interface{}{"title": `{{ template "missing" . }}`}},
+		}},
+	}
+	defer sinks.SetNamedTemplates(nil, nil)
+	assert.Error(t, config.Validate())
+
+	config.Receivers[0].Stdout.Layout["title"] = `{{ template "title" . }}`
+	assert.NoError(t, config.Validate())
+
+	config.Templates["title"] = "{{ .Reason"
+	assert.Error(t, config.Validate())
+}
+
+func TestValidate_RouteNamedTemplates(t *testing.T) {
+	config := Config{
+		Templates: map[string]string{"env": "prod"},
+		Route: Route{
+			Transforms: []sinks.TransformConfig{{Set: map[string]interface{}{"environment": `{{ template "env" . }}`}}},
+		},
+	}
+	defer sinks.SetNamedTemplates(nil, nil)
+	assert.NoError(t, config.Validate())
+
+	config.Route.Transforms[0].Set["environment"] = `{{ template "missing" . }}`
+	assert.Error(t, config.Validate())
+}
+
+func TestValidate_LayoutRef(t *testing.T) {
+	config := Config{
+		Layouts: map[string]map[string]interface{}{"short": {"msg": "{{ .Message }}"}},
+		Receivers: []sinks.ReceiverConfig{{
+			Name:   "stdout",
+			Stdout: &sinks.StdoutConfig{LayoutRef: "short"},
+		}},
+	}
+	defer sinks.SetLayouts(nil)
+	assert.NoError(t, config.Validate())
+	assert.Equal(t, "{{ .Message }}", config.Receivers[0].Stdout.Layout["msg"])
+
+	config.Receivers[0].Stdout = &sinks.StdoutConfig{LayoutRef: "long"}
+	assert.Error(t, config.Validate())
+
+	config.Layouts["short"]["msg"] = "{{ .Message"
+	config.Receivers[0].Stdout = &sinks.StdoutConfig{LayoutRef: "short"}
+	assert.Error(t, config.Validate())
+}
@@ -4,39 +4,92 @@ import (
 	"reflect"
 
 	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/resmoio/kubernetes-event-exporter/pkg/metrics"
+	"github.com/resmoio/kubernetes-event-exporter/pkg/sinks"
 	"github.com/rs/zerolog/log"
 )
 
 // Engine is responsible for initializing the receivers from sinks
 type Engine struct {
-	Route    Route
-	Registry ReceiverRegistry
+	Route           Route
+	Registry        ReceiverRegistry
+	DefaultReceiver string
+	MetricsStore    *metrics.Store
+	Severity        SeverityConfig
+
+	silenceable map[string]bool
 }
 
-func NewEngine(config *Config, registry ReceiverRegistry) *Engine {
+func NewEngine(config *Config, registry ReceiverRegistry, metricsStore *metrics.Store) *Engine {
+	silenceable := make(map[string]bool)
 	for _, v := range config.Receivers {
 		sink, err := v.GetSink()
 		if err != nil {
 			log.Fatal().Err(err).Str("name", v.Name).Msg("Cannot initialize sink")
 		}
 
+		// Redaction comes first so that nothing in the sink, not even aggregation keys, sees the sensitive data
+		sink, err = sinks.NewRedactor(sink, []*sinks.RedactionConfig{config.Redaction, v.Redaction}, func(n int) {
+			if metricsStore != nil {
+				metricsStore.Redactions.Add(float64(n))
+			}
+		})
+		if err != nil {
+			log.Fatal().Err(err).Str("name", v.Name).Msg("Cannot initialize redaction")
+		}
+
 		log.Info().
 			Str("name", v.Name).
 			Str("type", reflect.TypeOf(sink).String()).
 			Msg("Registering sink")
 
 		registry.Register(v.Name, sink)
+		silenceable[v.Name] = v.Silenceable
 	}
 
 	return &Engine{
-		Route:    config.Route,
-		Registry: registry,
+		Route:           config.Route,
+		Registry:        registry,
+		DefaultReceiver: config.DefaultReceiver,
+		MetricsStore:    metricsStore,
+		Severity:        config.Severity,
+		silenceable:     silenceable,
+	}
+}
+
+// SetSilences makes the engine check the silences before sending events to the silenceable receivers
+func (e *Engine) SetSilences(silences *SilenceStore) {
+	e.Registry = &silencingRegistry{
+		ReceiverRegistry: e.Registry,
+		silences:         silences,
+		silenceable:      e.silenceable,
+		onSilenced: func() {
+			if e.MetricsStore != nil {
+				e.MetricsStore.EventsSilenced.Inc()
+			}
+		},
 	}
 }
 
 // OnEvent does not care whether event is add or update. Prior filtering should be done in the controller/watcher
+// The event is classified with a severity before it is routed.
+// Events that are neither sent to a receiver nor dropped by the route tree go to the default receiver if one is
+// configured.
 func (e *Engine) OnEvent(event *kube.EnhancedEvent) {
-	e.Route.ProcessEvent(event, e.Registry)
+	event.Severity = e.Severity.Classify(event)
+
+	if e.Route.ProcessEvent(event, e.Registry) != RouteUnmatched {
+		return
+	}
+
+	if e.DefaultReceiver != "" {
+		e.Registry.SendEvent(e.DefaultReceiver, event)
+		return
+	}
+
+	if e.MetricsStore != nil {
+		e.MetricsStore.EventsUnrouted.Inc()
+	}
 }
 
 // Stop stops all registered sinks
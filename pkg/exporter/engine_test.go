@@ -1,7 +1,9 @@
 package exporter
 
 import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/resmoio/kubernetes-event-exporter/pkg/metrics"
 	"github.com/resmoio/kubernetes-event-exporter/pkg/sinks"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -13,7 +15,7 @@ func TestEngineNoRoutes(t *testing.T) {
 		Receivers: nil,
 	}
 
-	e := NewEngine(cfg, &SyncRegistry{})
+	e := NewEngine(cfg, &SyncRegistry{}, nil)
 	ev := &kube.EnhancedEvent{}
 	e.OnEvent(ev)
 }
@@ -32,7 +34,7 @@ func TestEngineSimple(t *testing.T) {
 		}},
 	}
 
-	e := NewEngine(cfg, &SyncRegistry{})
+	e := NewEngine(cfg, &SyncRegistry{}, nil)
 	ev := &kube.EnhancedEvent{}
 	e.OnEvent(ev)
 
@@ -56,10 +58,152 @@ func TestEngineDropSimple(t *testing.T) {
 		}},
 	}
 
-	e := NewEngine(cfg, &SyncRegistry{})
+	e := NewEngine(cfg, &SyncRegistry{}, nil)
 	ev := &kube.EnhancedEvent{}
 	e.OnEvent(ev)
 
 	assert.NotContains(t, config.Ref.Events, ev)
 	assert.Empty(t, config.Ref.Events)
 }
+
+func TestEngineDefaultReceiver(t *testing.T) {
+	matched := &sinks.InMemoryConfig{}
+	fallback := &sinks.InMemoryConfig{}
+	cfg := &Config{
+		Route: Route{
+			Match: []Rule{{
+				Namespace: "kube-system",
+				Receiver:  "matched",
+			}},
+		},
+		DefaultReceiver: "fallback",
+		Receivers: []sinks.ReceiverConfig{{
+			Name:     "matched",
+			InMemory: matched,
+		}, {
+			Name:     "fallback",
+			InMemory: fallback,
+		}},
+	}
+
+	e := NewEngine(cfg, &SyncRegistry{}, nil)
+	ev1 := &kube.EnhancedEvent{}
+	ev1.Namespace = "kube-system"
+	ev2 := &kube.EnhancedEvent{}
+	ev2.Namespace = "default"
+	e.OnEvent(ev1)
+	e.OnEvent(ev2)
+
+	assert.Equal(t, []*kube.EnhancedEvent{ev1}, matched.Ref.Events)
+	assert.Equal(t, []*kube.EnhancedEvent{ev2}, fallback.Ref.Events)
+}
+
+func TestEngineDropSkipsDefaultReceiver(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+
+	fallback := &sinks.InMemoryConfig{}
+	cfg := &Config{
+		Route: Route{
+			Drop: []Rule{{
+				Namespace: "kube-system",
+			}},
+		},
+		DefaultReceiver: "fallback",
+		Receivers: []sinks.ReceiverConfig{{
+			Name:     "fallback",
+			InMemory: fallback,
+		}},
+	}
+
+	e := NewEngine(cfg, &SyncRegistry{}, metricsStore)
+	dropped := &kube.EnhancedEvent{}
+	dropped.Namespace = "kube-system"
+	unmatched := &kube.EnhancedEvent{}
+	unmatched.Namespace = "default"
+	e.OnEvent(dropped)
+	e.OnEvent(unmatched)
+
+	assert.Equal(t, []*kube.EnhancedEvent{unmatched}, fallback.Ref.Events)
+	assert.Equal(t, float64(0), testutil.ToFloat64(metricsStore.EventsUnrouted))
+}
+
+func TestEngineUnroutedMetric(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+
+	cfg := &Config{
+		Route: Route{
+			Match: []Rule{{
+				Namespace: "kube-system",
+			}},
+		},
+	}
+
+	e := NewEngine(cfg, &SyncRegistry{}, metricsStore)
+	e.OnEvent(&kube.EnhancedEvent{})
+	assert.Equal(t, float64(1), testutil.ToFloat64(metricsStore.EventsUnrouted))
+}
+
+func TestEngineRedactionMetric(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+
+	config := &sinks.InMemoryConfig{}
+	cfg := &Config{
+		Redaction: &sinks.RedactionConfig{
+			Patterns: []sinks.RedactionPattern{{Regex: "token=[a-z0-9]+"}},
+		},
+		Route: Route{
+			Match: []Rule{{
+				Receiver: "in-mem",
+			}},
+		},
+		Receivers: []sinks.ReceiverConfig{{
+			Name:     "in-mem",
+			InMemory: config,
+		}},
+	}
+
+	e := NewEngine(cfg, &SyncRegistry{}, metricsStore)
+	ev := &kube.EnhancedEvent{}
+	ev.Message = "failed with token=abc123"
+	e.OnEvent(ev)
+
+	assert.Equal(t, "failed with [REDACTED]", config.Ref.Events[0].Message)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metricsStore.Redactions))
+}
+
+func TestEngineClassifiesSeverity(t *testing.T) {
+	config := &sinks.InMemoryConfig{}
+	cfg := &Config{
+		Severity: SeverityConfig{Rules: []SeverityRule{{
+			Rule:     Rule{Reason: "Unhealthy"},
+			Severity: "error",
+		}}},
+		Route: Route{
+			Match: []Rule{{
+				MinSeverity: "error",
+				Receiver:    "in-mem",
+			}},
+		},
+		Receivers: []sinks.ReceiverConfig{{
+			Name:     "in-mem",
+			InMemory: config,
+		}},
+	}
+
+	e := NewEngine(cfg, &SyncRegistry{}, nil)
+	ev1 := &kube.EnhancedEvent{}
+	ev1.Type = "Warning"
+	ev1.Reason = "Unhealthy"
+	e.OnEvent(ev1)
+	ev2 := &kube.EnhancedEvent{}
+	ev2.Type = "Warning"
+	ev2.Reason = "FailedScheduling"
+	e.OnEvent(ev2)
+
+	assert.Equal(t, []*kube.EnhancedEvent{ev1}, config.Ref.Events)
+	assert.Equal(t, "error", ev1.Severity)
+	assert.Equal(t, "warning", ev2.Severity)
+}
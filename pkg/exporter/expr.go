@@ -0,0 +1,102 @@
+package exporter
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+)
+
+// celEnv declares a single "event" variable which is the JSON view of the EnhancedEvent, so expressions use the
+// same field names as the JSON output, e.g. event.involvedObject.labels['tier']
+var celEnv = newCELEnv()
+
+// newCELEnv panics when the environment cannot be created, the declarations are fixed so it would fail on every start
+func newCELEnv() *cel.Env {
+	env, err := cel.NewEnv(
+		cel.Variable("event", cel.MapType(cel.StringType, cel.DynType)),
+		cel.CrossTypeNumericComparisons(true),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("cannot create the CEL environment: %v", err))
+	}
+	return env
+}
+
+// eventVars keeps the JSON view of the events for the expressions, so that an event is converted once however many
+// rules evaluate an expression against it. It is keyed by the event since transforms make new events while routing.
+type eventVars map[*kube.EnhancedEvent]map[string]interface{}
+
+func (v eventVars) get(ev *kube.EnhancedEvent) (map[string]interface{}, error) {
+	if vars, ok := v[ev]; ok {
+		return vars, nil
+	}
+	vars, err := ev.ToMap()
+	if err != nil {
+		return nil, err
+	}
+	v[ev] = vars
+	return vars, nil
+}
+
+// celPrograms caches compiled programs by expression text. Rules are copied by value while routing, so the
+// cache is kept here instead of in the rule to make sure every expression is compiled only once.
+var celPrograms = struct {
+	sync.RWMutex
+	m map[string]cel.Program
+}{m: make(map[string]cel.Program)}
+
+// compileExpr parses and type-checks the expression, which has to evaluate to a boolean.
+func compileExpr(expr string) (cel.Program, error) {
+	celPrograms.RLock()
+	prg, ok := celPrograms.m[expr]
+	celPrograms.RUnlock()
+	if ok {
+		return prg, nil
+	}
+
+	ast, issues := celEnv.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("expression must evaluate to bool, got %s", ast.OutputType())
+	}
+
+	prg, err := celEnv.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+
+	celPrograms.Lock()
+	celPrograms.m[expr] = prg
+	celPrograms.Unlock()
+	return prg, nil
+}
+
+// evalExpr evaluates the expression against the event. Any error, including a non-boolean result, is returned so
+// the caller can treat the rule as not matching.
+func evalExpr(expr string, ev *kube.EnhancedEvent, cache eventVars) (bool, error) {
+	prg, err := compileExpr(expr)
+	if err != nil {
+		return false, err
+	}
+
+	vars, err := cache.get(ev)
+	if err != nil {
+		return false, err
+	}
+
+	out, _, err := prg.Eval(map[string]interface{}{"event": vars})
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression returned %T instead of bool", out.Value())
+	}
+	return result, nil
+}
@@ -1,30 +1,119 @@
 package exporter
 
-import "github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+import (
+	"fmt"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/resmoio/kubernetes-event-exporter/pkg/sinks"
+	"github.com/rs/zerolog/log"
+)
 
 // Route allows using rules to drop events or match events to specific receivers.
 // It also allows using routes recursively for complex route building to fit
-// most of the needs
+// most of the needs. Continue controls whether the sibling routes are still processed
+// after this route sent the event to a receiver, it defaults to true. During the
+// MuteTimeIntervals the route and its sub routes do not send any events, but the events they match still count as
+// handled. Transforms change the events that
+// are not dropped before they are matched.
 type Route struct {
-	Drop   []Rule
-	Match  []Rule
-	Routes []Route
+	Drop              []Rule
+	Match             []Rule
+	Routes            []Route
+	Continue          *bool                   `yaml:"continue"`
+	MuteTimeIntervals []string                `yaml:"muteTimeIntervals"`
+	Transforms        []sinks.TransformConfig `yaml:"transforms"`
+
+	muteIntervals []*TimeInterval
+}
+
+// continues returns whether the sibling routes should be processed after this one handled an event
+func (r *Route) continues() bool {
+	return r.Continue == nil || *r.Continue
+}
+
+// Validate checks the rules of the route and its sub routes recursively and resolves the time intervals
+// they refer to
+func (r *Route) Validate(intervals map[string]*TimeInterval) error {
+	muteIntervals, err := resolveTimeIntervals(r.MuteTimeIntervals, intervals)
+	if err != nil {
+		return err
+	}
+	r.muteIntervals = muteIntervals
+
+	if err := sinks.ValidateTransforms(r.Transforms); err != nil {
+		return err
+	}
+
+	for i := range r.Drop {
+		if err := r.Drop[i].Validate(intervals); err != nil {
+			return fmt.Errorf("drop rule %d: %w", i, err)
+		}
+	}
+
+	for i := range r.Match {
+		if err := r.Match[i].Validate(intervals); err != nil {
+			return fmt.Errorf("match rule %d: %w", i, err)
+		}
+	}
+
+	for i := range r.Routes {
+		if err := r.Routes[i].Validate(intervals); err != nil {
+			return fmt.Errorf("route %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// RouteResult is what happened to an event in a route tree
+type RouteResult int
+
+const (
+	// RouteUnmatched means that no route sent the event to a receiver
+	RouteUnmatched RouteResult = iota
+	// RouteHandled means that the event was sent to at least one receiver
+	RouteHandled
+	// RouteDropped means that a drop rule matched the event and no other route sent it to a receiver
+	RouteDropped
+)
+
+// ProcessEvent routes the event through the route tree and returns whether it was handled, dropped or unmatched
+func (r *Route) ProcessEvent(ev *kube.EnhancedEvent, registry ReceiverRegistry) RouteResult {
+	return r.processEvent(ev, registry, false, eventVars{})
 }
 
-func (r *Route) ProcessEvent(ev *kube.EnhancedEvent, registry ReceiverRegistry) {
+// processEvent routes the event without sending it to the receivers when the route or a parent is muted. A muted
+// route still handles the events it matches, so continue and the default receiver behave as if it was active.
+func (r *Route) processEvent(ev *kube.EnhancedEvent, registry ReceiverRegistry, muted bool, vars eventVars) RouteResult {
+	if !muted && len(r.muteIntervals) > 0 && inAnyTimeInterval(r.muteIntervals, now()) {
+		muted = true
+	}
+
 	// First determine whether we will drop the event: If any of the drop is matched, we break the loop
 	for _, v := range r.Drop {
-		if v.MatchesEvent(ev) {
-			return
+		if v.matchesEvent(ev, vars) {
+			return RouteDropped
+		}
+	}
+
+	if len(r.Transforms) > 0 {
+		transformed, err := sinks.ApplyTransforms(ev, r.Transforms)
+		if err != nil {
+			log.Error().Err(err).Str("event", ev.Message).Msg("Cannot transform event")
+		} else {
+			ev = transformed
 		}
 	}
 
 	// It has match rules, it should go to the matchers
+	result := RouteUnmatched
 	matchesAll := true
 	for _, rule := range r.Match {
-		if rule.MatchesEvent(ev) {
+		if rule.matchesEvent(ev, vars) {
 			if rule.Receiver != "" {
-				registry.SendEvent(rule.Receiver, ev)
+				if !muted {
+					registry.SendEvent(rule.Receiver, ev)
+				}
+				result = RouteHandled
 				// Send the event down the hole
 			}
 		} else {
@@ -35,7 +124,23 @@ func (r *Route) ProcessEvent(ev *kube.EnhancedEvent, registry ReceiverRegistry)
 	// If all matches are satisfied, we can send them down to the rabbit hole
 	if matchesAll {
 		for _, subRoute := range r.Routes {
-			subRoute.ProcessEvent(ev, registry)
+			switch subRoute.processEvent(ev, registry, muted, vars) {
+			case RouteHandled:
+				result = RouteHandled
+			case RouteDropped:
+				// A sub route dropping the event does not stop its siblings
+				if result == RouteUnmatched {
+					result = RouteDropped
+				}
+				continue
+			default:
+				continue
+			}
+			// The route handled the event and asked us to stop here
+			if !subRoute.continues() {
+				break
+			}
 		}
 	}
+	return result
 }
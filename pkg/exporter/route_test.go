@@ -223,3 +223,167 @@ func Test_GHIssue51(t *testing.T) {
 	assert.True(t, reg.isEventRcvd("elastic", &ev1))
 	assert.False(t, reg.isEventRcvd("elastic", &ev2))
 }
+
+func TestRouteContinueFalseStopsSiblings(t *testing.T) {
+	ev := kube.EnhancedEvent{}
+	ev.Namespace = "kube-system"
+	reg := testReceiverRegistry{}
+
+	stop := false
+	r := Route{
+		Routes: []Route{{
+			Match: []Rule{{
+				Namespace: "default",
+				Receiver:  "default-ns",
+			}},
+			Continue: &stop,
+		}, {
+			Match: []Rule{{
+				Namespace: "kube-system",
+				Receiver:  "kube-system-ns",
+			}},
+			Continue: &stop,
+		}, {
+			Match: []Rule{{
+				Receiver: "all",
+			}},
+		}},
+	}
+
+	assert.Equal(t, RouteHandled, r.ProcessEvent(&ev, &reg))
+	assert.False(t, reg.isEventRcvd("default-ns", &ev))
+	assert.True(t, reg.isEventRcvd("kube-system-ns", &ev))
+	assert.False(t, reg.isEventRcvd("all", &ev))
+}
+
+func TestRouteContinueByDefault(t *testing.T) {
+	ev := kube.EnhancedEvent{}
+	ev.Namespace = "kube-system"
+	reg := testReceiverRegistry{}
+
+	r := Route{
+		Routes: []Route{{
+			Match: []Rule{{
+				Receiver: "first",
+			}},
+		}, {
+			Match: []Rule{{
+				Receiver: "second",
+			}},
+		}},
+	}
+
+	assert.Equal(t, RouteHandled, r.ProcessEvent(&ev, &reg))
+	assert.True(t, reg.isEventRcvd("first", &ev))
+	assert.True(t, reg.isEventRcvd("second", &ev))
+}
+
+func TestRouteNotSent(t *testing.T) {
+	ev := kube.EnhancedEvent{}
+	ev.Namespace = "kube-system"
+	reg := testReceiverRegistry{}
+
+	r := Route{
+		Routes: []Route{{
+			Match: []Rule{{
+				Namespace: "default",
+				Receiver:  "default-ns",
+			}},
+		}},
+	}
+
+	assert.Equal(t, RouteUnmatched, r.ProcessEvent(&ev, &reg))
+	assert.Empty(t, reg.rcvd)
+}
+
+func TestRouteDropped(t *testing.T) {
+	ev := kube.EnhancedEvent{}
+	ev.Namespace = "kube-system"
+	reg := testReceiverRegistry{}
+
+	r := Route{
+		Drop: []Rule{{
+			Namespace: "kube-system",
+		}},
+		Match: []Rule{{
+			Receiver: "all",
+		}},
+	}
+	assert.Equal(t, RouteDropped, r.ProcessEvent(&ev, &reg))
+	assert.Empty(t, reg.rcvd)
+
+	r = Route{
+		Routes: []Route{{
+			Drop: []Rule{{
+				Namespace: "kube-system",
+			}},
+			Match: []Rule{{
+				Receiver: "first",
+			}},
+		}, {
+			Match: []Rule{{
+				Namespace: "default",
+				Receiver:  "second",
+			}},
+		}},
+	}
+	assert.Equal(t, RouteDropped, r.ProcessEvent(&ev, &reg))
+	assert.Empty(t, reg.rcvd)
+
+	r.Routes[1].Match[0].Namespace = ""
+	assert.Equal(t, RouteHandled, r.ProcessEvent(&ev, &reg))
+	assert.False(t, reg.isEventRcvd("first", &ev))
+	assert.True(t, reg.isEventRcvd("second", &ev))
+}
+
+func TestRouteTransforms(t *testing.T) {
+	ev := kube.EnhancedEvent{}
+	ev.Namespace = "kube-system"
+	ev.Message = "Readiness probe failed"
+	reg := testReceiverRegistry{}
+
+	r := Route{
+		Transforms: []sinks.TransformConfig{{
+			Set: map[string]interface{}{"environment": "prod"},
+		}},
+		Routes: []Route{{
+			Match: []Rule{{
+				Receiver: "transformed",
+			}},
+		}},
+	}
+	assert.NoError(t, r.Validate(nil))
+
+	assert.Equal(t, RouteHandled, r.ProcessEvent(&ev, &reg))
+	assert.Len(t, reg.rcvd["transformed"], 1)
+	assert.Equal(t, "prod", reg.rcvd["transformed"][0].Fields["environment"])
+	assert.Equal(t, "Readiness probe failed", reg.rcvd["transformed"][0].Message)
+	assert.Nil(t, ev.Fields)
+
+	r.Transforms[0].Delete = []string{"message"}
+	assert.Error(t, r.Validate(nil))
+}
+
+func TestRouteExprOnTransformedEvent(t *testing.T) {
+	ev := kube.EnhancedEvent{}
+	ev.Namespace = "kube-system"
+	reg := testReceiverRegistry{}
+
+	// The expressions of the parent see the event, the ones of the sub route the transformed event
+	r := Route{
+		Match: []Rule{{Expr: "!has(event.environment)"}},
+		Routes: []Route{{
+			Transforms: []sinks.TransformConfig{{
+				Set: map[string]interface{}{"environment": "prod"},
+			}},
+			Match: []Rule{{Expr: "event.environment == 'prod'", Receiver: "prod"}},
+		}, {
+			Match: []Rule{{Expr: "!has(event.environment)", Receiver: "plain"}},
+		}},
+	}
+	assert.NoError(t, r.Validate(nil))
+
+	assert.Equal(t, RouteHandled, r.ProcessEvent(&ev, &reg))
+	assert.Len(t, reg.rcvd["prod"], 1)
+	assert.Len(t, reg.rcvd["plain"], 1)
+}
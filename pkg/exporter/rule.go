@@ -1,8 +1,11 @@
 package exporter
 
 import (
-	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"fmt"
 	"regexp"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/rs/zerolog/log"
 )
 
 // matchString is a method to clean the code. Error handling is omitted here because these
@@ -15,24 +18,76 @@ func matchString(pattern, s string) bool {
 
 // Rule is for matching an event
 type Rule struct {
-	Labels      map[string]string
-	Annotations map[string]string
-	Message     string
-	APIVersion  string `yaml:"apiVersion"`
-	Kind        string
-	Namespace   string
-	Reason      string
-	Type        string
-	MinCount    int32 `yaml:"minCount"`
-	Component   string
-	Host        string
-	Receiver    string
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Message     string            `json:"message,omitempty"`
+	APIVersion  string            `yaml:"apiVersion" json:"apiVersion,omitempty"`
+	Kind        string            `json:"kind,omitempty"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Reason      string            `json:"reason,omitempty"`
+	Type        string            `json:"type,omitempty"`
+	MinCount    int32             `yaml:"minCount" json:"minCount,omitempty"`
+	MinSeverity string            `yaml:"minSeverity" json:"minSeverity,omitempty"`
+	Component   string            `json:"component,omitempty"`
+	Host        string            `json:"host,omitempty"`
+	Expr        string            `json:"expr,omitempty"`
+	// ActiveTimeIntervals limits the rule to match only when the current time is in one of the named intervals
+	ActiveTimeIntervals []string `yaml:"activeTimeIntervals" json:"activeTimeIntervals,omitempty"`
+	Receiver            string   `json:"receiver,omitempty"`
+
+	activeIntervals []*TimeInterval
+}
+
+// Validate compiles the CEL expression of the rule if it has one so that broken expressions are caught while
+// loading the config instead of silently never matching. The time intervals are resolved from the given ones.
+func (r *Rule) Validate(intervals map[string]*TimeInterval) error {
+	if r.MinSeverity != "" {
+		if _, ok := kube.SeverityLevel(r.MinSeverity); !ok {
+			return fmt.Errorf("unknown minSeverity %q", r.MinSeverity)
+		}
+	}
+
+	if r.Expr != "" {
+		if _, err := compileExpr(r.Expr); err != nil {
+			return fmt.Errorf("invalid expr %q: %w", r.Expr, err)
+		}
+	}
+
+	activeIntervals, err := resolveTimeIntervals(r.ActiveTimeIntervals, intervals)
+	if err != nil {
+		return err
+	}
+	r.activeIntervals = activeIntervals
+	return nil
+}
+
+// patterns returns the regular expressions of the rule that compare event fields, labels and annotations
+func (r *Rule) patterns() []string {
+	var patterns []string
+	for _, p := range []string{r.Message, r.APIVersion, r.Kind, r.Namespace, r.Reason, r.Type, r.Component, r.Host} {
+		if p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	for _, p := range r.Labels {
+		patterns = append(patterns, p)
+	}
+	for _, p := range r.Annotations {
+		patterns = append(patterns, p)
+	}
+	return patterns
 }
 
 // MatchesEvent compares the rule to an event and returns a boolean value to indicate
 // whether the event is compatible with the rule. All fields are compared as regular expressions
-// so the user must keep that in mind while writing rules.
+// so the user must keep that in mind while writing rules. Expr is a CEL expression evaluated
+// against the JSON form of the event, which is available as the "event" variable.
 func (r *Rule) MatchesEvent(ev *kube.EnhancedEvent) bool {
+	return r.matchesEvent(ev, eventVars{})
+}
+
+// matchesEvent is MatchesEvent with the JSON view of the events shared by the rules evaluated for the same events
+func (r *Rule) matchesEvent(ev *kube.EnhancedEvent, vars eventVars) bool {
 	// These rules are just basic comparison rules, if one of them fails, it means the event does not match the rule
 	rules := [][2]string{
 		{r.Message, ev.Message},
@@ -85,12 +140,32 @@ func (r *Rule) MatchesEvent(ev *kube.EnhancedEvent) bool {
 	}
 
 	// If minCount is not given via a config, it's already 0 and the count is already 1 and this passes.
-	if ev.Count >= r.MinCount {
-		return true
-	} else {
+	if ev.Count < r.MinCount {
 		return false
 	}
 
+	if r.MinSeverity != "" {
+		minLevel, _ := kube.SeverityLevel(r.MinSeverity)
+		if level, _ := kube.SeverityLevel(ev.GetSeverity()); level < minLevel {
+			return false
+		}
+	}
+
+	// Intervals are resolved on validation, a rule that is not validated never matches instead of always matching
+	if len(r.ActiveTimeIntervals) > 0 && !inAnyTimeInterval(r.activeIntervals, now()) {
+		return false
+	}
+
+	// The expression is evaluated last since it is the most expensive matcher
+	if r.Expr != "" {
+		matches, err := evalExpr(r.Expr, ev, vars)
+		if err != nil {
+			log.Debug().Err(err).Str("expr", r.Expr).Msg("Cannot evaluate expression")
+			return false
+		}
+		return matches
+	}
+
 	// If it failed every step, it must match because our matchers are limiting
 	return true
 }
@@ -355,3 +355,69 @@ func TestCount(t *testing.T) {
 
 	assert.False(t, r.MatchesEvent(ev))
 }
+
+func TestExprRule(t *testing.T) {
+	ev := &kube.EnhancedEvent{}
+	ev.Reason = "FailedMount"
+	ev.Count = 5
+	ev.InvolvedObject.Labels = map[string]string{
+		"tier": "prod",
+	}
+
+	r := Rule{
+		Expr: "event.count > 3 && event.involvedObject.labels['tier'] == 'prod' && event.reason.startsWith('Failed')",
+	}
+	assert.NoError(t, r.Validate(nil))
+	assert.True(t, r.MatchesEvent(ev))
+
+	ev.Count = 2
+	assert.False(t, r.MatchesEvent(ev))
+}
+
+func TestExprRuleWithFieldMatchers(t *testing.T) {
+	ev := &kube.EnhancedEvent{}
+	ev.Namespace = "default"
+	ev.Reason = "BackOff"
+
+	r := Rule{
+		Namespace: "kube-system",
+		Expr:      "event.reason == 'BackOff'",
+	}
+	assert.False(t, r.MatchesEvent(ev))
+
+	r.Namespace = "default"
+	assert.True(t, r.MatchesEvent(ev))
+}
+
+func TestExprRuleMissingKeyDoesNotMatch(t *testing.T) {
+	ev := &kube.EnhancedEvent{}
+
+	r := Rule{
+		Expr: "event.involvedObject.labels['tier'] == 'prod'",
+	}
+	assert.False(t, r.MatchesEvent(ev))
+}
+
+func TestExprRuleValidate(t *testing.T) {
+	assert.Error(t, (&Rule{Expr: "event.reason =="}).Validate(nil))
+	assert.Error(t, (&Rule{Expr: "unknown.reason == 'x'"}).Validate(nil))
+	assert.Error(t, (&Rule{Expr: "'not a bool'"}).Validate(nil))
+	assert.NoError(t, (&Rule{Expr: "event.type == 'Warning'"}).Validate(nil))
+}
+
+func TestExprRulesShareEventVars(t *testing.T) {
+	ev := &kube.EnhancedEvent{}
+	ev.Reason = "BackOff"
+	vars := eventVars{}
+
+	first := Rule{Expr: "event.reason == 'BackOff'"}
+	second := Rule{Expr: "event.reason != 'Started'"}
+	assert.True(t, first.matchesEvent(ev, vars))
+	assert.True(t, second.matchesEvent(ev, vars))
+	assert.Len(t, vars, 1)
+
+	// The event is converted once, a change afterwards is not seen by the rules sharing the variables
+	ev.Reason = "Started"
+	assert.True(t, second.matchesEvent(ev, vars))
+	assert.False(t, second.MatchesEvent(ev))
+}
@@ -0,0 +1,49 @@
+package exporter
+
+import (
+	"fmt"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+)
+
+// SeverityConfig classifies the events as info, warning, error or critical. The first matching rule gives the
+// severity, then the built-in table of reasons unless it is disabled, then the type of the event.
+type SeverityConfig struct {
+	Rules           []SeverityRule `yaml:"rules"`
+	DisableDefaults bool           `yaml:"disableDefaults"`
+}
+
+// SeverityRule gives its severity to the events matching the rule
+type SeverityRule struct {
+	Rule     `yaml:",inline"`
+	Severity string `yaml:"severity"`
+}
+
+// Validate checks the severities and the rules
+func (c *SeverityConfig) Validate(intervals map[string]*TimeInterval) error {
+	for i := range c.Rules {
+		if _, ok := kube.SeverityLevel(c.Rules[i].Severity); !ok {
+			return fmt.Errorf("severity rule %d: unknown severity %q", i, c.Rules[i].Severity)
+		}
+		if err := c.Rules[i].Rule.Validate(intervals); err != nil {
+			return fmt.Errorf("severity rule %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Classify returns the severity of the event
+func (c *SeverityConfig) Classify(ev *kube.EnhancedEvent) string {
+	vars := eventVars{}
+	for i := range c.Rules {
+		if c.Rules[i].matchesEvent(ev, vars) {
+			return c.Rules[i].Severity
+		}
+	}
+	if !c.DisableDefaults {
+		if severity, ok := kube.ReasonSeverity(ev.Reason); ok {
+			return severity
+		}
+	}
+	return kube.TypeSeverity(ev.Type)
+}
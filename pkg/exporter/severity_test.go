@@ -0,0 +1,76 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestSeverityConfig_Classify(t *testing.T) {
+	var cfg SeverityConfig
+	err := yaml.Unmarshal([]byte(`
+rules:
+  - reason: "FailedScheduling"
+    message: "Insufficient (cpu|memory)"
+    severity: error
+  - reason: "BackOff"
+    minCount: 10
+    severity: critical
+`), &cfg)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate(nil))
+
+	ev := &kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.Reason = "FailedScheduling"
+	ev.Message = "0/3 nodes are available: 3 Insufficient memory."
+	assert.Equal(t, kube.SeverityError, cfg.Classify(ev))
+
+	ev.Message = "0/3 nodes are available: 3 node(s) had taint."
+	assert.Equal(t, kube.SeverityWarning, cfg.Classify(ev))
+
+	ev.Reason = "BackOff"
+	ev.Count = 3
+	assert.Equal(t, kube.SeverityError, cfg.Classify(ev))
+	ev.Count = 12
+	assert.Equal(t, kube.SeverityCritical, cfg.Classify(ev))
+
+	// NodeNotReady is a Normal event but it is critical in the built-in table
+	ev = &kube.EnhancedEvent{}
+	ev.Type = "Normal"
+	ev.Reason = "NodeNotReady"
+	assert.Equal(t, kube.SeverityCritical, cfg.Classify(ev))
+	cfg.DisableDefaults = true
+	assert.Equal(t, kube.SeverityInfo, cfg.Classify(ev))
+}
+
+func TestSeverityConfig_Validate(t *testing.T) {
+	cfg := SeverityConfig{Rules: []SeverityRule{{Severity: "fatal"}}}
+	assert.Error(t, cfg.Validate(nil))
+
+	cfg = SeverityConfig{Rules: []SeverityRule{{Rule: Rule{Expr: "event.count >"}, Severity: "error"}}}
+	assert.Error(t, cfg.Validate(nil))
+}
+
+func TestRule_MinSeverity(t *testing.T) {
+	r := Rule{MinSeverity: "error"}
+	require.NoError(t, r.Validate(nil))
+
+	ev := &kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.Reason = "Unhealthy"
+	assert.False(t, r.MatchesEvent(ev))
+
+	ev.Severity = kube.SeverityCritical
+	assert.True(t, r.MatchesEvent(ev))
+
+	// Events that were not classified use the built-in severity
+	ev.Severity = ""
+	ev.Reason = "Evicted"
+	assert.True(t, r.MatchesEvent(ev))
+
+	assert.Error(t, (&Rule{MinSeverity: "high"}).Validate(nil))
+}
@@ -0,0 +1,413 @@
+package exporter
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/rs/zerolog/log"
+)
+
+// SilencesConfig enables the silences API. When ConfigMap is set, silences are persisted in it so that they survive
+// restarts of the exporter, and they are reloaded from it every ReloadIntervalSeconds so that the silences created
+// through another replica apply too. The API is served on Address, or on the metrics address when it is empty, and
+// requires BearerToken when it is set.
+type SilencesConfig struct {
+	Enabled               bool   `yaml:"enabled"`
+	Namespace             string `yaml:"namespace"`
+	ConfigMap             string `yaml:"configMap"`
+	ReloadIntervalSeconds int    `yaml:"reloadIntervalSeconds"`
+	Address               string `yaml:"address"`
+	BearerToken           string `yaml:"bearerToken"`
+}
+
+// Validate rejects a negative reload interval, an unset interval defaults to 30 seconds
+func (c *SilencesConfig) Validate() error {
+	if c.ReloadIntervalSeconds < 0 {
+		return fmt.Errorf("silences reloadIntervalSeconds must be positive, not %d", c.ReloadIntervalSeconds)
+	}
+	if c.ReloadIntervalSeconds == 0 {
+		c.ReloadIntervalSeconds = 30
+	}
+	return nil
+}
+
+// Silence mutes the events matching any of its matchers for the receivers that are marked as silenceable, until
+// it expires. If a matcher has a receiver, it is compared to the receiver name as a regular expression so the
+// silence can be limited to some receivers.
+type Silence struct {
+	ID        string    `json:"id"`
+	Matchers  []Rule    `json:"matchers"`
+	CreatedBy string    `json:"createdBy"`
+	Comment   string    `json:"comment"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+}
+
+// Validate checks the silence before it is stored
+func (s *Silence) Validate() error {
+	if len(s.Matchers) == 0 {
+		return errors.New("silence must have at least one matcher")
+	}
+	if s.CreatedBy == "" {
+		return errors.New("silence must have createdBy")
+	}
+	if s.Comment == "" {
+		return errors.New("silence must have a comment")
+	}
+	if !s.EndsAt.After(s.StartsAt) {
+		return errors.New("silence must end after it starts")
+	}
+
+	for i := range s.Matchers {
+		m := &s.Matchers[i]
+		// Time intervals are not available to silences since a silence has its own time range
+		if err := m.Validate(nil); err != nil {
+			return fmt.Errorf("matcher %d: %w", i, err)
+		}
+
+		// An empty matcher would mute every event of every receiver
+		patterns := m.patterns()
+		if len(patterns) == 0 && m.Receiver == "" && m.MinCount == 0 && m.MinSeverity == "" && m.Expr == "" {
+			return fmt.Errorf("matcher %d has no conditions", i)
+		}
+		if m.Receiver != "" {
+			patterns = append(patterns, m.Receiver)
+		}
+		for _, p := range patterns {
+			if _, err := regexp.Compile(p); err != nil {
+				return fmt.Errorf("matcher %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// IsActive returns whether the silence is in effect at the given time
+func (s *Silence) IsActive(tm time.Time) bool {
+	return !tm.Before(s.StartsAt) && tm.Before(s.EndsAt)
+}
+
+// Matches returns whether the silence mutes the event for the receiver
+func (s *Silence) Matches(receiver string, ev *kube.EnhancedEvent) bool {
+	vars := eventVars{}
+	for _, m := range s.Matchers {
+		if m.Receiver != "" && !matchString(m.Receiver, receiver) {
+			continue
+		}
+		if m.matchesEvent(ev, vars) {
+			return true
+		}
+	}
+	return false
+}
+
+// SilencePersister stores the serialized silences, see kube.ConfigMapStore
+type SilencePersister interface {
+	Load(ctx context.Context) ([]byte, error)
+	Save(ctx context.Context, data []byte) error
+}
+
+// SilenceStore keeps the silences in memory and writes them to the persister, if there is one, on every change.
+// It also serves the HTTP API to manage them.
+type SilenceStore struct {
+	mu        sync.RWMutex
+	silences  map[string]*Silence
+	persister SilencePersister
+}
+
+func NewSilenceStore(persister SilencePersister) *SilenceStore {
+	return &SilenceStore{
+		silences:  make(map[string]*Silence),
+		persister: persister,
+	}
+}
+
+// Load replaces the silences with the ones of the persister, expired ones are removed on the next save
+func (s *SilenceStore) Load(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(ctx)
+}
+
+// load must be called with the lock held
+func (s *SilenceStore) load(ctx context.Context) error {
+	if s.persister == nil {
+		return nil
+	}
+
+	data, err := s.persister.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	var silences []*Silence
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &silences); err != nil {
+			return err
+		}
+	}
+
+	loaded := make(map[string]*Silence, len(silences))
+	for _, sil := range silences {
+		if err := sil.Validate(); err != nil {
+			log.Warn().Err(err).Str("id", sil.ID).Msg("Ignoring invalid silence")
+			continue
+		}
+		loaded[sil.ID] = sil
+	}
+	s.silences = loaded
+	return nil
+}
+
+// Reload loads the silences from the persister every interval until the context is done, so that the changes made
+// by other replicas of the exporter apply
+func (s *SilenceStore) Reload(ctx context.Context, interval time.Duration) {
+	if s.persister == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Load(ctx); err != nil {
+				log.Error().Err(err).Msg("Cannot reload silences")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Add validates and stores a new silence, it gets a new ID and starts now if the start is not given
+func (s *SilenceStore) Add(ctx context.Context, sil Silence) (*Silence, error) {
+	id, err := newSilenceID()
+	if err != nil {
+		return nil, err
+	}
+	sil.ID = id
+	if sil.StartsAt.IsZero() {
+		sil.StartsAt = now()
+	}
+
+	if err := sil.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The persisted silences are loaded first so that the ones of other replicas are not overwritten
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+	s.silences[sil.ID] = &sil
+	if err := s.save(ctx); err != nil {
+		delete(s.silences, sil.ID)
+		return nil, err
+	}
+	return &sil, nil
+}
+
+// Delete removes a silence, it returns false if the silence does not exist
+func (s *SilenceStore) Delete(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(ctx); err != nil {
+		return false, err
+	}
+	sil, ok := s.silences[id]
+	if !ok {
+		return false, nil
+	}
+
+	delete(s.silences, id)
+	if err := s.save(ctx); err != nil {
+		s.silences[id] = sil
+		return false, err
+	}
+	return true, nil
+}
+
+// List returns the silences that are not expired, ordered by their end
+func (s *SilenceStore) List() []Silence {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	current := now()
+	list := make([]Silence, 0, len(s.silences))
+	for _, sil := range s.silences {
+		if current.Before(sil.EndsAt) {
+			list = append(list, *sil)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].EndsAt.Before(list[j].EndsAt) })
+	return list
+}
+
+// IsSilenced returns whether an active silence mutes the event for the receiver
+func (s *SilenceStore) IsSilenced(receiver string, ev *kube.EnhancedEvent) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	current := now()
+	for _, sil := range s.silences {
+		if sil.IsActive(current) && sil.Matches(receiver, ev) {
+			return true
+		}
+	}
+	return false
+}
+
+// save must be called with the lock held, expired silences are removed before saving
+func (s *SilenceStore) save(ctx context.Context) error {
+	current := now()
+	list := make([]*Silence, 0, len(s.silences))
+	for id, sil := range s.silences {
+		if !current.Before(sil.EndsAt) {
+			delete(s.silences, id)
+			continue
+		}
+		list = append(list, sil)
+	}
+
+	if s.persister == nil {
+		return nil
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	data, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return s.persister.Save(ctx, data)
+}
+
+func newSilenceID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// SilencesAPIPath is where the silences API is served
+const SilencesAPIPath = "/api/v1/silences"
+
+// silenceRequest is the body to create a silence. Either endsAt or duration (e.g. "2h") must be given.
+type silenceRequest struct {
+	Silence
+	Duration string `json:"duration"`
+}
+
+// ServeHTTP serves the silences API:
+//
+//	GET    /api/v1/silences       lists the active and pending silences
+//	POST   /api/v1/silences       creates a silence
+//	DELETE /api/v1/silences/{id}  expires a silence
+func (s *SilenceStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, SilencesAPIPath), "/")
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		writeJSON(w, http.StatusOK, s.List())
+	case r.Method == http.MethodPost && id == "":
+		var req silenceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if req.Duration != "" {
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			if req.StartsAt.IsZero() {
+				req.StartsAt = now()
+			}
+			req.EndsAt = req.StartsAt.Add(d)
+		}
+
+		sil, err := s.Add(r.Context(), req.Silence)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		log.Info().Str("id", sil.ID).Str("createdBy", sil.CreatedBy).Time("endsAt", sil.EndsAt).Msg("Silence created")
+		writeJSON(w, http.StatusCreated, sil)
+	case r.Method == http.MethodDelete && id != "":
+		found, err := s.Delete(r.Context(), id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if !found {
+			writeError(w, http.StatusNotFound, fmt.Errorf("silence %s not found", id))
+			return
+		}
+		log.Info().Str("id", id).Msg("Silence deleted")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s is not allowed", r.Method))
+	}
+}
+
+// RequireBearerToken only passes the requests with the token in the Authorization header to the handler, it returns
+// the handler as it is when the token is empty
+func RequireBearerToken(token string, handler http.Handler) http.Handler {
+	if token == "" {
+		return handler
+	}
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Debug().Err(err).Msg("Cannot write response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// silencingRegistry does not pass the events to the silenceable receivers when they are silenced
+type silencingRegistry struct {
+	ReceiverRegistry
+	silences    *SilenceStore
+	silenceable map[string]bool
+	onSilenced  func()
+}
+
+func (r *silencingRegistry) SendEvent(name string, event *kube.EnhancedEvent) {
+	if r.silenceable[name] && r.silences.IsSilenced(name, event) {
+		log.Debug().Str("sink", name).Str("event", event.Message).Msg("Event is silenced")
+		if r.onSilenced != nil {
+			r.onSilenced()
+		}
+		return
+	}
+	r.ReceiverRegistry.SendEvent(name, event)
+}
@@ -0,0 +1,251 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/resmoio/kubernetes-event-exporter/pkg/sinks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryPersister keeps the saved silences so tests can check what is persisted
+type memoryPersister struct {
+	data []byte
+}
+
+func (m *memoryPersister) Load(context.Context) ([]byte, error) {
+	return m.data, nil
+}
+
+func (m *memoryPersister) Save(_ context.Context, data []byte) error {
+	m.data = data
+	return nil
+}
+
+func TestSilenceStoreLifecycle(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	withNow(t, start)
+
+	persister := &memoryPersister{}
+	store := NewSilenceStore(persister)
+	sil, err := store.Add(ctx, Silence{
+		Matchers:  []Rule{{Reason: "FailedScheduling"}},
+		CreatedBy: "on-call",
+		Comment:   "scheduling storm",
+		EndsAt:    start.Add(2 * time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, start, sil.StartsAt)
+
+	ev := &kube.EnhancedEvent{}
+	ev.Reason = "FailedScheduling"
+	assert.True(t, store.IsSilenced("opsgenie", ev))
+
+	// A restarted exporter loads the silence back
+	loaded := NewSilenceStore(persister)
+	require.NoError(t, loaded.Load(ctx))
+	assert.Len(t, loaded.List(), 1)
+	assert.True(t, loaded.IsSilenced("opsgenie", ev))
+
+	withNow(t, start.Add(2*time.Hour))
+	assert.False(t, store.IsSilenced("opsgenie", ev))
+	assert.Empty(t, store.List())
+
+	found, err := store.Delete(ctx, sil.ID)
+	require.NoError(t, err)
+	assert.True(t, found)
+}
+
+func TestSilenceStoreReplicas(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	withNow(t, start)
+
+	persister := &memoryPersister{}
+	leader := NewSilenceStore(persister)
+	replica := NewSilenceStore(persister)
+
+	ev := &kube.EnhancedEvent{}
+	ev.Reason = "FailedScheduling"
+	sil, err := replica.Add(ctx, Silence{
+		Matchers:  []Rule{{Reason: "FailedScheduling"}},
+		CreatedBy: "on-call",
+		Comment:   "created through a replica",
+		EndsAt:    start.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.False(t, leader.IsSilenced("opsgenie", ev))
+	require.NoError(t, leader.Load(ctx))
+	assert.True(t, leader.IsSilenced("opsgenie", ev))
+
+	// Adding through the leader keeps the silence of the replica
+	_, err = leader.Add(ctx, Silence{
+		Matchers:  []Rule{{Reason: "BackOff"}},
+		CreatedBy: "on-call",
+		Comment:   "created through the leader",
+		EndsAt:    start.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.NoError(t, replica.Load(ctx))
+	assert.Len(t, replica.List(), 2)
+
+	found, err := replica.Delete(ctx, sil.ID)
+	require.NoError(t, err)
+	assert.True(t, found)
+	require.NoError(t, leader.Load(ctx))
+	assert.False(t, leader.IsSilenced("opsgenie", ev))
+	assert.Len(t, leader.List(), 1)
+}
+
+func TestSilencesBearerToken(t *testing.T) {
+	handler := RequireBearerToken("secret", NewSilenceStore(nil))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, SilencesAPIPath, nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, SilencesAPIPath, nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, SilencesAPIPath, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestSilencesConfigValidate(t *testing.T) {
+	cfg := SilencesConfig{}
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, 30, cfg.ReloadIntervalSeconds)
+
+	cfg.ReloadIntervalSeconds = -1
+	assert.Error(t, cfg.Validate())
+}
+
+func TestSilenceMatcherReceiver(t *testing.T) {
+	sil := Silence{
+		Matchers: []Rule{{Namespace: "default", Receiver: "slack"}},
+	}
+
+	ev := &kube.EnhancedEvent{}
+	ev.Namespace = "default"
+	assert.True(t, sil.Matches("slack", ev))
+	assert.False(t, sil.Matches("opsgenie", ev))
+}
+
+func TestSilenceValidate(t *testing.T) {
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	valid := Silence{
+		Matchers:  []Rule{{Reason: "BackOff"}},
+		CreatedBy: "on-call",
+		Comment:   "known issue",
+		StartsAt:  start,
+		EndsAt:    start.Add(time.Hour),
+	}
+	assert.NoError(t, valid.Validate())
+
+	invalid := []func(s *Silence){
+		func(s *Silence) { s.Matchers = nil },
+		func(s *Silence) { s.CreatedBy = "" },
+		func(s *Silence) { s.Comment = "" },
+		func(s *Silence) { s.EndsAt = start },
+		func(s *Silence) { s.Matchers[0].Expr = "event.reason ==" },
+		func(s *Silence) { s.Matchers[0].ActiveTimeIntervals = []string{"business-hours"} },
+		func(s *Silence) { s.Matchers[0] = Rule{} },
+		func(s *Silence) { s.Matchers[0].Reason = "Back(Off" },
+		func(s *Silence) { s.Matchers[0].Labels = map[string]string{"app": "[nginx"} },
+		func(s *Silence) { s.Matchers[0].Receiver = "slack|(" },
+	}
+	for _, modify := range invalid {
+		s := valid
+		s.Matchers = []Rule{valid.Matchers[0]}
+		modify(&s)
+		assert.Error(t, s.Validate())
+	}
+}
+
+func TestSilencesAPI(t *testing.T) {
+	withNow(t, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	store := NewSilenceStore(nil)
+
+	body := []byte(`{"matchers": [{"reason": "FailedScheduling"}], "createdBy": "on-call", "comment": "storm", "duration": "2h"}`)
+	rec := httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, SilencesAPIPath, bytes.NewReader(body)))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var created Silence
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, "FailedScheduling", created.Matchers[0].Reason)
+	assert.Equal(t, time.Date(2026, 10, 19, 14, 0, 0, 0, time.UTC), created.EndsAt)
+
+	rec = httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, SilencesAPIPath, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list []Silence
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	assert.Len(t, list, 1)
+
+	rec = httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, SilencesAPIPath, bytes.NewReader([]byte(`{"comment": "no matchers"}`))))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, SilencesAPIPath+"/"+created.ID, nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, SilencesAPIPath+"/"+created.ID, nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestEngineSilenceableReceivers(t *testing.T) {
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	withNow(t, start)
+
+	paging := &sinks.InMemoryConfig{}
+	dump := &sinks.InMemoryConfig{}
+	cfg := &Config{
+		Route: Route{
+			Match: []Rule{{Receiver: "paging"}, {Receiver: "dump"}},
+		},
+		Receivers: []sinks.ReceiverConfig{{
+			Name:        "paging",
+			Silenceable: true,
+			InMemory:    paging,
+		}, {
+			Name:     "dump",
+			InMemory: dump,
+		}},
+	}
+
+	store := NewSilenceStore(nil)
+	_, err := store.Add(context.Background(), Silence{
+		Matchers:  []Rule{{Reason: "FailedScheduling"}},
+		CreatedBy: "on-call",
+		Comment:   "storm",
+		EndsAt:    start.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	e := NewEngine(cfg, &SyncRegistry{}, nil)
+	e.SetSilences(store)
+
+	ev := &kube.EnhancedEvent{}
+	ev.Reason = "FailedScheduling"
+	e.OnEvent(ev)
+
+	assert.Empty(t, paging.Ref.Events)
+	assert.Contains(t, dump.Ref.Events, ev)
+}
@@ -0,0 +1,237 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// now is replaced in tests to check time interval matching against a fixed clock
+var now = time.Now
+
+// TimeInterval describes a recurring period of time, similar to the Alertmanager time intervals. Every given field
+// has to match for a time to be in the interval, empty fields match everything. Ranges are written as "start:end"
+// and are inclusive, e.g. weekdays: ["monday:friday"], times: [{start: "09:00", end: "17:00"}].
+type TimeInterval struct {
+	Name        string      `yaml:"name"`
+	Location    string      `yaml:"location"`
+	Times       []TimeRange `yaml:"times"`
+	Weekdays    []string    `yaml:"weekdays"`
+	DaysOfMonth []string    `yaml:"daysOfMonth"`
+	Months      []string    `yaml:"months"`
+	Years       []string    `yaml:"years"`
+
+	location    *time.Location
+	times       []intRange
+	weekdays    []intRange
+	daysOfMonth []intRange
+	months      []intRange
+	years       []intRange
+}
+
+// TimeRange is a range of time in a day. Start is inclusive and end is exclusive, "24:00" can be used as the end
+// of the day.
+type TimeRange struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+type intRange struct {
+	start int
+	end   int
+}
+
+var weekdayNames = map[string]int{
+	"sunday": 0, "monday": 1, "tuesday": 2, "wednesday": 3, "thursday": 4, "friday": 5, "saturday": 6,
+}
+
+var monthNames = map[string]int{
+	"january": 1, "february": 2, "march": 3, "april": 4, "may": 5, "june": 6,
+	"july": 7, "august": 8, "september": 9, "october": 10, "november": 11, "december": 12,
+}
+
+// Validate parses all the fields of the interval so that it can be used for matching
+func (t *TimeInterval) Validate() error {
+	if t.Name == "" {
+		return errors.New("time interval must have a name")
+	}
+
+	var err error
+	t.location = time.UTC
+	if t.Location != "" {
+		if t.location, err = time.LoadLocation(t.Location); err != nil {
+			return fmt.Errorf("time interval %s: %w", t.Name, err)
+		}
+	}
+
+	t.times = make([]intRange, 0, len(t.Times))
+	for _, tr := range t.Times {
+		start, err := parseMinuteOfDay(tr.Start)
+		if err != nil {
+			return fmt.Errorf("time interval %s: %w", t.Name, err)
+		}
+		end, err := parseMinuteOfDay(tr.End)
+		if err != nil {
+			return fmt.Errorf("time interval %s: %w", t.Name, err)
+		}
+		if start >= end {
+			return fmt.Errorf("time interval %s: start %s must be before end %s", t.Name, tr.Start, tr.End)
+		}
+		t.times = append(t.times, intRange{start: start, end: end})
+	}
+
+	if t.weekdays, err = parseRanges(t.Weekdays, weekdayNames, 0, 6); err != nil {
+		return fmt.Errorf("time interval %s: weekdays: %w", t.Name, err)
+	}
+	if t.daysOfMonth, err = parseRanges(t.DaysOfMonth, nil, -31, 31); err != nil {
+		return fmt.Errorf("time interval %s: daysOfMonth: %w", t.Name, err)
+	}
+	if t.months, err = parseRanges(t.Months, monthNames, 1, 12); err != nil {
+		return fmt.Errorf("time interval %s: months: %w", t.Name, err)
+	}
+	if t.years, err = parseRanges(t.Years, nil, 0, 9999); err != nil {
+		return fmt.Errorf("time interval %s: years: %w", t.Name, err)
+	}
+	return nil
+}
+
+// ContainsTime returns whether the given time is in the interval. Validate must be called before.
+func (t *TimeInterval) ContainsTime(tm time.Time) bool {
+	if t.location != nil {
+		tm = tm.In(t.location)
+	}
+
+	if len(t.times) > 0 {
+		// End of the time range is exclusive so that 09:00-17:00 does not include 17:00
+		minute := tm.Hour()*60 + tm.Minute()
+		matches := false
+		for _, r := range t.times {
+			if minute >= r.start && minute < r.end {
+				matches = true
+				break
+			}
+		}
+		if !matches {
+			return false
+		}
+	}
+
+	if len(t.weekdays) > 0 && !inRanges(t.weekdays, int(tm.Weekday())) {
+		return false
+	}
+
+	if len(t.daysOfMonth) > 0 {
+		// Negative days count from the end of the month, -1 is the last day
+		daysInMonth := time.Date(tm.Year(), tm.Month()+1, 0, 0, 0, 0, 0, tm.Location()).Day()
+		resolved := make([]intRange, len(t.daysOfMonth))
+		for i, r := range t.daysOfMonth {
+			resolved[i] = r
+			if r.start < 0 {
+				resolved[i].start = daysInMonth + r.start + 1
+			}
+			if r.end < 0 {
+				resolved[i].end = daysInMonth + r.end + 1
+			}
+		}
+		if !inRanges(resolved, tm.Day()) {
+			return false
+		}
+	}
+
+	if len(t.months) > 0 && !inRanges(t.months, int(tm.Month())) {
+		return false
+	}
+
+	if len(t.years) > 0 && !inRanges(t.years, tm.Year()) {
+		return false
+	}
+	return true
+}
+
+func inRanges(ranges []intRange, value int) bool {
+	for _, r := range ranges {
+		if value >= r.start && value <= r.end {
+			return true
+		}
+	}
+	return false
+}
+
+func parseMinuteOfDay(s string) (int, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour*60+minute > 24*60 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return hour*60 + minute, nil
+}
+
+func parseRanges(values []string, names map[string]int, min, max int) ([]intRange, error) {
+	ranges := make([]intRange, 0, len(values))
+	for _, v := range values {
+		parts := strings.SplitN(v, ":", 2)
+		start, err := parseRangeValue(parts[0], names, min, max)
+		if err != nil {
+			return nil, err
+		}
+		end := start
+		if len(parts) == 2 {
+			if end, err = parseRangeValue(parts[1], names, min, max); err != nil {
+				return nil, err
+			}
+		}
+		// Negative values are resolved at matching time, so they can only be compared if both have the same sign
+		if (start < 0) == (end < 0) && start > end {
+			return nil, fmt.Errorf("invalid range %q, start is after end", v)
+		}
+		ranges = append(ranges, intRange{start: start, end: end})
+	}
+	return ranges, nil
+}
+
+func parseRangeValue(s string, names map[string]int, min, max int) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if v, ok := names[s]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < min || v > max || v == 0 && min < 0 {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
+
+// inAnyTimeInterval is true if the time is in at least one of the intervals
+func inAnyTimeInterval(intervals []*TimeInterval, tm time.Time) bool {
+	for _, ti := range intervals {
+		if ti.ContainsTime(tm) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveTimeIntervals looks up the intervals by name
+func resolveTimeIntervals(names []string, intervals map[string]*TimeInterval) ([]*TimeInterval, error) {
+	resolved := make([]*TimeInterval, 0, len(names))
+	for _, name := range names {
+		ti, ok := intervals[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown time interval %q", name)
+		}
+		resolved = append(resolved, ti)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,144 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withNow(t *testing.T, tm time.Time) {
+	old := now
+	now = func() time.Time { return tm }
+	t.Cleanup(func() { now = old })
+}
+
+func TestTimeIntervalBusinessHours(t *testing.T) {
+	ti := TimeInterval{
+		Name:     "business-hours",
+		Location: "Europe/Berlin",
+		Weekdays: []string{"monday:friday"},
+		Times:    []TimeRange{{Start: "09:00", End: "17:00"}},
+	}
+	require.NoError(t, ti.Validate())
+
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	// 2026-10-19 is a Monday
+	assert.True(t, ti.ContainsTime(time.Date(2026, 10, 19, 9, 0, 0, 0, berlin)))
+	assert.True(t, ti.ContainsTime(time.Date(2026, 10, 19, 16, 59, 0, 0, berlin)))
+	assert.False(t, ti.ContainsTime(time.Date(2026, 10, 19, 17, 0, 0, 0, berlin)))
+	assert.False(t, ti.ContainsTime(time.Date(2026, 10, 18, 12, 0, 0, 0, berlin)))
+	// 07:30 UTC is 09:30 in Berlin
+	assert.True(t, ti.ContainsTime(time.Date(2026, 10, 19, 7, 30, 0, 0, time.UTC)))
+}
+
+func TestTimeIntervalMaintenanceWindow(t *testing.T) {
+	ti := TimeInterval{
+		Name:        "node-upgrades",
+		Years:       []string{"2026"},
+		Months:      []string{"october"},
+		DaysOfMonth: []string{"-1"},
+		Times:       []TimeRange{{Start: "22:00", End: "24:00"}},
+	}
+	require.NoError(t, ti.Validate())
+
+	assert.True(t, ti.ContainsTime(time.Date(2026, 10, 31, 23, 59, 0, 0, time.UTC)))
+	assert.False(t, ti.ContainsTime(time.Date(2026, 10, 30, 23, 0, 0, 0, time.UTC)))
+	assert.False(t, ti.ContainsTime(time.Date(2027, 10, 31, 23, 0, 0, 0, time.UTC)))
+}
+
+func TestTimeIntervalValidate(t *testing.T) {
+	invalid := []TimeInterval{
+		{},
+		{Name: "a", Location: "Nowhere/City"},
+		{Name: "a", Times: []TimeRange{{Start: "17:00", End: "09:00"}}},
+		{Name: "a", Times: []TimeRange{{Start: "9", End: "17:00"}}},
+		{Name: "a", Weekdays: []string{"friday:monday"}},
+		{Name: "a", Weekdays: []string{"funday"}},
+		{Name: "a", DaysOfMonth: []string{"0"}},
+		{Name: "a", Months: []string{"13"}},
+	}
+
+	for _, ti := range invalid {
+		assert.Error(t, ti.Validate(), "%+v", ti)
+	}
+}
+
+func TestRuleActiveTimeIntervals(t *testing.T) {
+	intervals := map[string]*TimeInterval{
+		"weekdays": {Name: "weekdays", Weekdays: []string{"monday:friday"}},
+	}
+	require.NoError(t, intervals["weekdays"].Validate())
+
+	r := Rule{ActiveTimeIntervals: []string{"weekdays"}}
+	require.NoError(t, r.Validate(intervals))
+
+	withNow(t, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	assert.True(t, r.MatchesEvent(&kube.EnhancedEvent{}))
+
+	withNow(t, time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	assert.False(t, r.MatchesEvent(&kube.EnhancedEvent{}))
+
+	assert.Error(t, (&Rule{ActiveTimeIntervals: []string{"missing"}}).Validate(intervals))
+}
+
+func TestRouteMuteTimeIntervals(t *testing.T) {
+	intervals := map[string]*TimeInterval{
+		"upgrades": {Name: "upgrades", DaysOfMonth: []string{"19"}},
+	}
+	require.NoError(t, intervals["upgrades"].Validate())
+
+	r := Route{
+		Routes: []Route{{
+			Match:             []Rule{{Receiver: "opsgenie"}},
+			MuteTimeIntervals: []string{"upgrades"},
+		}, {
+			Match: []Rule{{Receiver: "dump"}},
+		}},
+	}
+	require.NoError(t, r.Validate(intervals))
+
+	ev := &kube.EnhancedEvent{}
+	reg := testReceiverRegistry{}
+	withNow(t, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	r.ProcessEvent(ev, &reg)
+	assert.False(t, reg.isEventRcvd("opsgenie", ev))
+	assert.True(t, reg.isEventRcvd("dump", ev))
+
+	withNow(t, time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC))
+	r.ProcessEvent(ev, &reg)
+	assert.True(t, reg.isEventRcvd("opsgenie", ev))
+}
+
+func TestRouteMutedStillHandles(t *testing.T) {
+	intervals := map[string]*TimeInterval{
+		"upgrades": {Name: "upgrades", DaysOfMonth: []string{"19"}},
+	}
+	require.NoError(t, intervals["upgrades"].Validate())
+
+	stop := false
+	r := Route{
+		Routes: []Route{{
+			Match:             []Rule{{Receiver: "pager"}},
+			MuteTimeIntervals: []string{"upgrades"},
+			Continue:          &stop,
+		}, {
+			Match: []Rule{{Receiver: "dump"}},
+		}},
+	}
+	require.NoError(t, r.Validate(intervals))
+
+	ev := &kube.EnhancedEvent{}
+	reg := testReceiverRegistry{}
+	withNow(t, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, RouteHandled, r.ProcessEvent(ev, &reg))
+	assert.Equal(t, 0, reg.count("pager"))
+	assert.Equal(t, 0, reg.count("dump"))
+
+	withNow(t, time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, RouteHandled, r.ProcessEvent(ev, &reg))
+	assert.Equal(t, 1, reg.count("pager"))
+	assert.Equal(t, 0, reg.count("dump"))
+}
@@ -0,0 +1,77 @@
+package kube
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ConfigMapStore keeps a single value in a key of a ConfigMap so that small state like silences survives restarts.
+// The ConfigMap is created on the first save if it does not exist.
+type ConfigMapStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	key       string
+}
+
+// NewConfigMapStore creates a store, if namespace is empty the namespace the exporter runs in is used
+func NewConfigMapStore(client kubernetes.Interface, namespace, name, key string) *ConfigMapStore {
+	if namespace == "" {
+		var err error
+		namespace, err = getInClusterNamespace()
+		if err != nil {
+			namespace = defaultNamespace
+		}
+	}
+
+	return &ConfigMapStore{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		key:       key,
+	}
+}
+
+// Load returns the stored value, or nil if the ConfigMap or the key does not exist yet
+func (s *ConfigMapStore) Load(ctx context.Context) ([]byte, error) {
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if val, ok := cm.Data[s.key]; ok {
+		return []byte(val), nil
+	}
+	return nil, nil
+}
+
+// Save replaces the stored value
+func (s *ConfigMapStore) Save(ctx context.Context, data []byte) error {
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+	cm, err := configMaps.Get(ctx, s.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.name,
+				Namespace: s.namespace,
+			},
+			Data: map[string]string{s.key: string(data)},
+		}, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[s.key] = string(data)
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,31 @@
+package kube
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConfigMapStore(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	store := NewConfigMapStore(client, "monitoring", "silences", "silences.json")
+
+	data, err := store.Load(ctx)
+	require.NoError(t, err)
+	require.Nil(t, data)
+
+	require.NoError(t, store.Save(ctx, []byte("[1]")))
+	require.NoError(t, store.Save(ctx, []byte("[2]")))
+
+	data, err = store.Load(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "[2]", string(data))
+
+	cm, err := client.CoreV1().ConfigMaps("monitoring").Get(ctx, "silences", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "[2]", cm.Data["silences.json"])
+}
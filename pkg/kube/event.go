@@ -1,7 +1,9 @@
 package kube
 
 import (
+	"bytes"
 	"encoding/json"
+	"reflect"
 	"strings"
 	"time"
 
@@ -12,6 +14,138 @@ type EnhancedEvent struct {
 	corev1.Event   `json:",inline"`
 	ClusterName    string                  `json:"clusterName"`
 	InvolvedObject EnhancedObjectReference `json:"involvedObject"`
+	// Severity is info, warning, error or critical, see GetSeverity
+	Severity string `json:"severity,omitempty"`
+	// Fields are added by the transforms, they are serialized at the top level of the event
+	Fields map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON adds the fields set by the transforms to the JSON of the event
+func (e EnhancedEvent) MarshalJSON() ([]byte, error) {
+	type plain EnhancedEvent
+	b, err := json.Marshal(plain(e))
+	if err != nil || len(e.Fields) == 0 {
+		return b, err
+	}
+
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	for k, v := range e.Fields {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		m[k] = raw
+	}
+	return json.Marshal(m)
+}
+
+// ToMap converts the event to its generic JSON representation. Numbers are kept as int64 when possible so that
+// expressions like event.count > 3 behave as they read.
+func (e *EnhancedEvent) ToMap() (map[string]interface{}, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	// metav1.Time is serialized in seconds, the map keeps the fractions so that EventFromMap restores the same times
+	setNanoTime(m, e.FirstTimestamp.Time, "firstTimestamp")
+	setNanoTime(m, e.LastTimestamp.Time, "lastTimestamp")
+	if metadata, ok := m["metadata"].(map[string]interface{}); ok {
+		setNanoTime(metadata, e.CreationTimestamp.Time, "creationTimestamp")
+	}
+	return NormalizeNumbers(m).(map[string]interface{}), nil
+}
+
+func setNanoTime(m map[string]interface{}, tm time.Time, key string) {
+	if !tm.IsZero() {
+		m[key] = tm.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// EventFromMap is the reverse of ToMap, the keys that are not fields of the event are kept in Fields
+func EventFromMap(m map[string]interface{}) (*EnhancedEvent, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	ev := &EnhancedEvent{}
+	if err := json.Unmarshal(b, ev); err != nil {
+		return nil, err
+	}
+
+	for k, v := range m {
+		if eventJSONFields[k] {
+			continue
+		}
+		if ev.Fields == nil {
+			ev.Fields = make(map[string]interface{})
+		}
+		ev.Fields[k] = v
+	}
+	return ev, nil
+}
+
+// eventJSONFields are the top level keys of the JSON of an event
+var eventJSONFields = jsonFieldNames(reflect.TypeOf(EnhancedEvent{}))
+
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		// Embedded structs without a name are inlined
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for n := range jsonFieldNames(field.Type) {
+				names[n] = true
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// NormalizeNumbers converts the json.Number values decoded with UseNumber to int64 or float64, so integers do not
+// become floats
+func NormalizeNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = NormalizeNumbers(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = NormalizeNumbers(item)
+		}
+		return val
+	}
+	return v
 }
 
 // DeDot replaces all dots in the labels and annotations with underscores. This is required for example in the
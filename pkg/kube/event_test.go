@@ -5,6 +5,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"testing"
+	"time"
 )
 
 func TestEnhancedEvent_DeDot(t *testing.T) {
@@ -102,3 +103,43 @@ func TestEnhancedEvent_DeDot_MustNotAlternateOriginal(t *testing.T) {
 	in.DeDot()
 	assert.EqualValues(t, expected, in)
 }
+
+func TestEnhancedEvent_MapRoundTrip(t *testing.T) {
+	in := &EnhancedEvent{}
+	in.Reason = "BackOff"
+	in.Count = 3
+	in.InvolvedObject.Labels = map[string]string{"app": "nginx"}
+	in.Fields = map[string]interface{}{"region": "eu-west-1"}
+
+	m, err := in.ToMap()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), m["count"])
+	assert.Equal(t, "eu-west-1", m["region"])
+
+	m["environment"] = "prod"
+	out, err := EventFromMap(m)
+	assert.NoError(t, err)
+	assert.Equal(t, "BackOff", out.Reason)
+	assert.Equal(t, int32(3), out.Count)
+	assert.Equal(t, "nginx", out.InvolvedObject.Labels["app"])
+	assert.Equal(t, map[string]interface{}{"region": "eu-west-1", "environment": "prod"}, out.Fields)
+}
+
+func TestEnhancedEvent_MapRoundTripTimes(t *testing.T) {
+	first := time.Date(2026, 10, 19, 12, 0, 0, 123456789, time.UTC)
+	in := &EnhancedEvent{}
+	in.FirstTimestamp = metav1.NewTime(first)
+	in.LastTimestamp = metav1.NewTime(first.Add(1500 * time.Millisecond))
+	in.EventTime = metav1.NewMicroTime(first.Truncate(time.Microsecond))
+	in.CreationTimestamp = metav1.NewTime(first)
+
+	m, err := in.ToMap()
+	assert.NoError(t, err)
+	out, err := EventFromMap(m)
+	assert.NoError(t, err)
+
+	assert.True(t, first.Equal(out.FirstTimestamp.Time), out.FirstTimestamp.String())
+	assert.True(t, first.Add(1500*time.Millisecond).Equal(out.LastTimestamp.Time), out.LastTimestamp.String())
+	assert.True(t, in.EventTime.Time.Equal(out.EventTime.Time), out.EventTime.String())
+	assert.True(t, first.Equal(out.CreationTimestamp.Time), out.CreationTimestamp.String())
+}
@@ -0,0 +1,59 @@
+package kube
+
+// Severities of the events from the lowest to the highest
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityError    = "error"
+	SeverityCritical = "critical"
+)
+
+var severityLevels = map[string]int{
+	SeverityInfo:     0,
+	SeverityWarning:  1,
+	SeverityError:    2,
+	SeverityCritical: 3,
+}
+
+// SeverityLevel returns the order of a severity to compare them, false if it is not a severity
+func SeverityLevel(severity string) (int, bool) {
+	level, ok := severityLevels[severity]
+	return level, ok
+}
+
+// reasonSeverities are the reasons that usually need more attention than their type tells, whatever their type is
+var reasonSeverities = map[string]string{
+	"OOMKilling":         SeverityCritical,
+	"NodeNotReady":       SeverityCritical,
+	"SystemOOM":          SeverityCritical,
+	"Evicted":            SeverityError,
+	"FailedMount":        SeverityError,
+	"FailedAttachVolume": SeverityError,
+	"BackOff":            SeverityError,
+	"FailedCreate":       SeverityError,
+}
+
+// ReasonSeverity returns the built-in severity of a reason, false if it has none
+func ReasonSeverity(reason string) (string, bool) {
+	severity, ok := reasonSeverities[reason]
+	return severity, ok
+}
+
+// TypeSeverity is warning for Warning events and info for the others
+func TypeSeverity(eventType string) string {
+	if eventType == "Warning" {
+		return SeverityWarning
+	}
+	return SeverityInfo
+}
+
+// GetSeverity returns the severity the event was classified with, or the built-in one if it was not classified
+func (e *EnhancedEvent) GetSeverity() string {
+	if e.Severity != "" {
+		return e.Severity
+	}
+	if severity, ok := ReasonSeverity(e.Reason); ok {
+		return severity
+	}
+	return TypeSeverity(e.Type)
+}
@@ -15,6 +15,9 @@ type Store struct {
 	EventsDiscarded prometheus.Counter
 	WatchErrors     prometheus.Counter
 	SendErrors	    prometheus.Counter
+	EventsUnrouted  prometheus.Counter
+	EventsSilenced  prometheus.Counter
+	Redactions      prometheus.Counter
 }
 
 func Init(addr string) {
@@ -53,6 +56,18 @@ func NewMetricsStore(name_prefix string) *Store {
 			Name: name_prefix + "send_event_errors",
 			Help: "The total number of send event errors",
 		}),
+		EventsUnrouted: promauto.NewCounter(prometheus.CounterOpts{
+			Name: name_prefix + "events_unrouted",
+			Help: "The total number of events that were not sent to any receiver",
+		}),
+		EventsSilenced: promauto.NewCounter(prometheus.CounterOpts{
+			Name: name_prefix + "events_silenced",
+			Help: "The total number of events not sent to a receiver because of a silence",
+		}),
+		Redactions: promauto.NewCounter(prometheus.CounterOpts{
+			Name: name_prefix + "redactions",
+			Help: "The total number of values redacted, dropped or hashed in the events before sending them",
+		}),
 	}
 }
 
@@ -61,5 +76,8 @@ func DestroyMetricsStore(store *Store) {
 	prometheus.Unregister(store.EventsDiscarded)
 	prometheus.Unregister(store.WatchErrors)
 	prometheus.Unregister(store.SendErrors)
+	prometheus.Unregister(store.EventsUnrouted)
+	prometheus.Unregister(store.EventsSilenced)
+	prometheus.Unregister(store.Redactions)
 	store = nil
 }
@@ -0,0 +1,160 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultAggregationKey = "{{ .Namespace }}/{{ .InvolvedObject.Kind }}/{{ .InvolvedObject.Name }}/{{ .Reason }}"
+
+// AggregationConfig makes a receiver forward only the first of the events with the same key in a window, and a
+// summary of the repeated ones when the window ends.
+type AggregationConfig struct {
+	// Key is a template to group the events, defaults to namespace, kind, name and reason
+	Key           string `yaml:"key"`
+	WindowSeconds int    `yaml:"windowSeconds"`
+}
+
+// aggregationWindow tracks the events of one key since its first occurrence
+type aggregationWindow struct {
+	start time.Time
+	count int32
+	last  *kube.EnhancedEvent
+}
+
+// Aggregator wraps a sink to deduplicate the events sent to it. The first event of a key is sent right away, the
+// next ones with the same key are counted and when the window ends, the last of them is sent as a summary with the
+// number of occurrences in its message and count.
+type Aggregator struct {
+	sink    Sink
+	key     string
+	window  time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	sendMu  sync.Mutex
+	windows map[string]*aggregationWindow
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+// Validate rejects a negative window, an unset window defaults to 5 minutes
+func (c *AggregationConfig) Validate() error {
+	if c.WindowSeconds < 0 {
+		return fmt.Errorf("aggregation windowSeconds must be positive, not %d", c.WindowSeconds)
+	}
+	return nil
+}
+
+func NewAggregator(sink Sink, cfg *AggregationConfig) *Aggregator {
+	if cfg.Key == "" {
+		cfg.Key = defaultAggregationKey
+	}
+	if cfg.WindowSeconds == 0 {
+		cfg.WindowSeconds = 300
+	}
+
+	a := &Aggregator{
+		sink:    sink,
+		key:     cfg.Key,
+		window:  time.Duration(cfg.WindowSeconds) * time.Second,
+		now:     time.Now,
+		windows: make(map[string]*aggregationWindow),
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+func (a *Aggregator) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	key, err := GetString(ev, a.key)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	if w, ok := a.windows[key]; ok {
+		w.count++
+		w.last = ev
+		a.mu.Unlock()
+		return nil
+	}
+	a.windows[key] = &aggregationWindow{start: a.now(), count: 1}
+	a.mu.Unlock()
+
+	return a.send(ctx, ev)
+}
+
+// send serializes the calls to the sink since the summaries are sent from another goroutine
+func (a *Aggregator) send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	a.sendMu.Lock()
+	defer a.sendMu.Unlock()
+	return a.sink.Send(ctx, ev)
+}
+
+func (a *Aggregator) run() {
+	defer close(a.doneCh)
+	ticker := time.NewTicker(a.tickInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			a.flush(false)
+		case <-a.stopCh:
+			a.flush(true)
+			return
+		}
+	}
+}
+
+func (a *Aggregator) tickInterval() time.Duration {
+	if a.window < 10*time.Second {
+		return a.window
+	}
+	return a.window / 10
+}
+
+// flush sends the summaries of the windows that ended, or of all of them if all is true
+func (a *Aggregator) flush(all bool) {
+	current := a.now()
+	summaries := make([]*kube.EnhancedEvent, 0)
+
+	a.mu.Lock()
+	for key, w := range a.windows {
+		if !all && current.Sub(w.start) < a.window {
+			continue
+		}
+		delete(a.windows, key)
+		if w.last != nil {
+			summaries = append(summaries, summarize(w, current.Sub(w.start)))
+		}
+	}
+	a.mu.Unlock()
+
+	for _, ev := range summaries {
+		if err := a.send(context.Background(), ev); err != nil {
+			log.Debug().Err(err).Str("event", ev.Message).Msg("Cannot send aggregated event")
+		}
+	}
+}
+
+// summarize creates the summary from the last event of the window
+func summarize(w *aggregationWindow, elapsed time.Duration) *kube.EnhancedEvent {
+	ev := *w.last
+	ev.Count = w.count
+	ev.Message = fmt.Sprintf("%s (occurred %d times in %s)", ev.Message, w.count, elapsed.Round(time.Second))
+	return &ev
+}
+
+// Close sends the pending summaries and closes the wrapped sink
+func (a *Aggregator) Close() {
+	close(a.stopCh)
+	<-a.doneCh
+	a.sink.Close()
+}
@@ -0,0 +1,102 @@
+package sinks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregator(t *testing.T) {
+	inner := &InMemory{}
+	a := NewAggregator(inner, &AggregationConfig{WindowSeconds: 3600})
+	current := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return current }
+
+	newEvent := func(name, message string) *kube.EnhancedEvent {
+		ev := &kube.EnhancedEvent{}
+		ev.Namespace = "default"
+		ev.InvolvedObject.Kind = "Pod"
+		ev.InvolvedObject.Name = name
+		ev.Reason = "BackOff"
+		ev.Message = message
+		return ev
+	}
+
+	first := newEvent("web-1", "Back-off restarting failed container")
+	require.NoError(t, a.Send(context.Background(), first))
+	for i := 0; i < 56; i++ {
+		require.NoError(t, a.Send(context.Background(), newEvent("web-1", "Back-off restarting failed container")))
+	}
+	other := newEvent("web-2", "Back-off restarting failed container")
+	require.NoError(t, a.Send(context.Background(), other))
+
+	// Only the first occurrences are forwarded while the window is open
+	assert.Equal(t, []*kube.EnhancedEvent{first, other}, inner.Events)
+
+	current = current.Add(time.Hour)
+	a.flush(false)
+	require.Len(t, inner.Events, 3)
+	assert.Equal(t, "Back-off restarting failed container (occurred 57 times in 1h0m0s)", inner.Events[2].Message)
+	assert.Equal(t, int32(57), inner.Events[2].Count)
+
+	// A new window starts after the previous one is flushed
+	next := newEvent("web-1", "Back-off restarting failed container")
+	require.NoError(t, a.Send(context.Background(), next))
+	assert.Equal(t, next, inner.Events[3])
+
+	a.Close()
+	assert.Len(t, inner.Events, 4)
+}
+
+func TestAggregatorCustomKey(t *testing.T) {
+	inner := &InMemory{}
+	a := NewAggregator(inner, &AggregationConfig{Key: "{{ .Reason }}", WindowSeconds: 3600})
+
+	ev1 := &kube.EnhancedEvent{}
+	ev1.Reason = "FailedScheduling"
+	ev1.InvolvedObject.Name = "a"
+	ev2 := &kube.EnhancedEvent{}
+	ev2.Reason = "FailedScheduling"
+	ev2.InvolvedObject.Name = "b"
+
+	require.NoError(t, a.Send(context.Background(), ev1))
+	require.NoError(t, a.Send(context.Background(), ev2))
+	assert.Equal(t, []*kube.EnhancedEvent{ev1}, inner.Events)
+
+	// Closing sends the pending summaries
+	a.Close()
+	require.Len(t, inner.Events, 2)
+	assert.Equal(t, "b", inner.Events[1].InvolvedObject.Name)
+	assert.Equal(t, int32(2), inner.Events[1].Count)
+}
+
+func TestReceiverConfigAggregation(t *testing.T) {
+	r := ReceiverConfig{
+		Name:        "aggregated",
+		InMemory:    &InMemoryConfig{},
+		Aggregation: &AggregationConfig{},
+	}
+
+	sink, err := r.GetSink()
+	require.NoError(t, err)
+	defer sink.Close()
+
+	_, ok := sink.(*Aggregator)
+	assert.True(t, ok)
+}
+
+func TestReceiverConfigAggregationWindow(t *testing.T) {
+	r := ReceiverConfig{
+		Name:        "aggregated",
+		InMemory:    &InMemoryConfig{},
+		Aggregation: &AggregationConfig{WindowSeconds: -1},
+	}
+	assert.Error(t, r.Validate())
+
+	r.Aggregation.WindowSeconds = 0
+	assert.NoError(t, r.Validate())
+}
@@ -0,0 +1,219 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/rs/zerolog/log"
+)
+
+// AMQPConfig publishes the events to an exchange of an AMQP 0.9.1 broker like RabbitMQ. The routing key and the
+// values of the headers are templates. Every publish waits for the confirmation of the broker and the messages are
+// persistent unless Transient is set. The connection is made on the first event, so that a broker that is down does
+// not stop the exporter, and a closed channel or connection is opened again on the next event.
+type AMQPConfig struct {
+	URL            string                 `yaml:"url"`
+	Exchange       string                 `yaml:"exchange"`
+	RoutingKey     string                 `yaml:"routingKey"`
+	Headers        map[string]string      `yaml:"headers"`
+	Transient      bool                   `yaml:"transient"`
+	Layout         map[string]interface{} `yaml:"layout"`
+	LayoutRef      string                 `yaml:"layoutRef"`
+	TimeoutSeconds int                    `yaml:"timeoutSeconds"`
+	TLS            *TLS                   `yaml:"tls"`
+}
+
+// amqpChannel and amqpConnection are the parts of the AMQP client the sink uses
+type amqpChannel interface {
+	Confirm(noWait bool) error
+	NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	IsClosed() bool
+	Close() error
+}
+
+type amqpConnection interface {
+	channel() (amqpChannel, error)
+	IsClosed() bool
+	Close() error
+}
+
+type amqpClientConnection struct {
+	*amqp.Connection
+}
+
+func (c amqpClientConnection) channel() (amqpChannel, error) {
+	ch, err := c.Channel()
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+type AMQPSink struct {
+	cfg     *AMQPConfig
+	dial    func() (amqpConnection, error)
+	timeout time.Duration
+
+	mu       sync.Mutex
+	conn     amqpConnection
+	ch       amqpChannel
+	confirms chan amqp.Confirmation
+}
+
+func NewAMQPSink(cfg *AMQPConfig) (Sink, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("amqp url is required")
+	}
+	if _, err := amqp.ParseURI(cfg.URL); err != nil {
+		return nil, fmt.Errorf("invalid amqp url: %w", err)
+	}
+	config := amqp.Config{
+		Heartbeat:  10 * time.Second,
+		Properties: amqp.Table{"connection_name": "kubernetes-event-exporter"},
+	}
+	if cfg.TLS != nil {
+		tlsConfig, err := setupTLS(cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup TLS: %w", err)
+		}
+		config.TLSClientConfig = tlsConfig
+	}
+
+	return newAMQPSink(cfg, func() (amqpConnection, error) {
+		conn, err := amqp.DialConfig(cfg.URL, config)
+		if err != nil {
+			return nil, err
+		}
+		return amqpClientConnection{conn}, nil
+	}), nil
+}
+
+func newAMQPSink(cfg *AMQPConfig, dial func() (amqpConnection, error)) *AMQPSink {
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 10
+	}
+	return &AMQPSink{
+		cfg:     cfg,
+		dial:    dial,
+		timeout: time.Duration(cfg.TimeoutSeconds) * time.Second,
+	}
+}
+
+// open connects and opens a channel in confirm mode when they are closed
+func (a *AMQPSink) open() error {
+	if a.conn == nil || a.conn.IsClosed() {
+		a.ch = nil
+		conn, err := a.dial()
+		if err != nil {
+			return err
+		}
+		a.conn = conn
+	}
+
+	if a.ch == nil || a.ch.IsClosed() {
+		ch, err := a.conn.channel()
+		if err != nil {
+			return err
+		}
+		if err := ch.Confirm(false); err != nil {
+			_ = ch.Close()
+			return err
+		}
+		a.ch = ch
+		a.confirms = ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	}
+	return nil
+}
+
+func (a *AMQPSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	body, err := serializeEventWithLayout(a.cfg.Layout, ev)
+	if err != nil {
+		return err
+	}
+	routingKey, err := GetString(ev, a.cfg.RoutingKey)
+	if err != nil {
+		return err
+	}
+
+	msg := amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    time.Now(),
+		Body:         body,
+	}
+	if a.cfg.Transient {
+		msg.DeliveryMode = amqp.Transient
+	}
+	if len(a.cfg.Headers) > 0 {
+		msg.Headers = make(amqp.Table, len(a.cfg.Headers))
+		for k, v := range a.cfg.Headers {
+			value, err := GetString(ev, v)
+			if err != nil {
+				return err
+			}
+			msg.Headers[k] = value
+		}
+	}
+
+	// The confirmations come in the order of the publishes, so only one publish is in flight
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		err = a.open()
+		if err == nil {
+			err = a.publish(ctx, routingKey, msg)
+		}
+		if err == nil || attempt > 0 {
+			return err
+		}
+		log.Debug().Err(err).Msg("AMQP publish failed, the channel is opened again")
+		a.closeChannel()
+	}
+}
+
+func (a *AMQPSink) publish(ctx context.Context, routingKey string, msg amqp.Publishing) error {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	if err := a.ch.PublishWithContext(ctx, a.cfg.Exchange, routingKey, false, false, msg); err != nil {
+		return err
+	}
+
+	select {
+	case confirm, ok := <-a.confirms:
+		if !ok {
+			return errors.New("amqp channel closed before the publish was confirmed")
+		}
+		if !confirm.Ack {
+			return fmt.Errorf("amqp broker rejected the message %d", confirm.DeliveryTag)
+		}
+		return nil
+	case <-ctx.Done():
+		// The channel is closed so that a late confirmation is not taken for the next publish
+		a.closeChannel()
+		return fmt.Errorf("amqp publish was not confirmed: %w", ctx.Err())
+	}
+}
+
+func (a *AMQPSink) closeChannel() {
+	if a.ch != nil {
+		_ = a.ch.Close()
+		a.ch = nil
+	}
+}
+
+func (a *AMQPSink) Close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.closeChannel()
+	if a.conn != nil {
+		_ = a.conn.Close()
+	}
+}
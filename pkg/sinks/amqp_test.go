@@ -0,0 +1,161 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type amqpPublished struct {
+	exchange string
+	key      string
+	msg      amqp.Publishing
+}
+
+// fakeAMQPBroker confirms the publishes unless nack is set, and a publish fails once the channel is broken
+type fakeAMQPBroker struct {
+	dials     int
+	channels  int
+	nack      bool
+	published []amqpPublished
+}
+
+type fakeAMQPConnection struct {
+	broker *fakeAMQPBroker
+	closed bool
+}
+
+func (c *fakeAMQPConnection) channel() (amqpChannel, error) {
+	c.broker.channels++
+	return &fakeAMQPChannel{broker: c.broker}, nil
+}
+
+func (c *fakeAMQPConnection) IsClosed() bool { return c.closed }
+
+func (c *fakeAMQPConnection) Close() error {
+	c.closed = true
+	return nil
+}
+
+type fakeAMQPChannel struct {
+	broker   *fakeAMQPBroker
+	confirm  bool
+	confirms chan amqp.Confirmation
+	tag      uint64
+	closed   bool
+}
+
+func (c *fakeAMQPChannel) Confirm(noWait bool) error {
+	c.confirm = true
+	return nil
+}
+
+func (c *fakeAMQPChannel) NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation {
+	c.confirms = confirm
+	return confirm
+}
+
+func (c *fakeAMQPChannel) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	if c.closed {
+		return amqp.ErrClosed
+	}
+	c.tag++
+	c.broker.published = append(c.broker.published, amqpPublished{exchange: exchange, key: key, msg: msg})
+	c.confirms <- amqp.Confirmation{DeliveryTag: c.tag, Ack: c.confirm && !c.broker.nack}
+	return nil
+}
+
+func (c *fakeAMQPChannel) IsClosed() bool { return c.closed }
+
+func (c *fakeAMQPChannel) Close() error {
+	c.closed = true
+	return nil
+}
+
+func newFakeAMQPSink(cfg *AMQPConfig, broker *fakeAMQPBroker) *AMQPSink {
+	return newAMQPSink(cfg, func() (amqpConnection, error) {
+		broker.dials++
+		return &fakeAMQPConnection{broker: broker}, nil
+	})
+}
+
+func TestAMQPSink_Send(t *testing.T) {
+	broker := &fakeAMQPBroker{}
+	sink := newFakeAMQPSink(&AMQPConfig{
+		Exchange:   "events",
+		RoutingKey: "{{ .InvolvedObject.Namespace }}.{{ .Reason }}",
+		Headers:    map[string]string{"type": "{{ .Type }}"},
+		Layout:     map[string]interface{}{"reason": "{{ .Reason }}"},
+	}, broker)
+	defer sink.Close()
+
+	assert.NoError(t, sink.Send(context.Background(), newTestEvent()))
+	assert.Len(t, broker.published, 1)
+
+	published := broker.published[0]
+	assert.Equal(t, "events", published.exchange)
+	assert.Equal(t, "default.BackOff", published.key)
+	assert.Equal(t, amqp.Persistent, published.msg.DeliveryMode)
+	assert.Equal(t, amqp.Table{"type": "Warning"}, published.msg.Headers)
+	assert.Equal(t, `{"reason":"BackOff"}`, string(published.msg.Body))
+}
+
+func TestAMQPSink_Nack(t *testing.T) {
+	broker := &fakeAMQPBroker{nack: true}
+	sink := newFakeAMQPSink(&AMQPConfig{Transient: true}, broker)
+	defer sink.Close()
+
+	// The nacked message is published again on a new channel before it fails
+	assert.Error(t, sink.Send(context.Background(), newTestEvent()))
+	assert.Len(t, broker.published, 2)
+	assert.Equal(t, amqp.Transient, broker.published[0].msg.DeliveryMode)
+	assert.Equal(t, 2, broker.channels)
+}
+
+func TestAMQPSink_Recovery(t *testing.T) {
+	broker := &fakeAMQPBroker{}
+	sink := newFakeAMQPSink(&AMQPConfig{RoutingKey: "events"}, broker)
+	defer sink.Close()
+
+	// The connection is made on the first event
+	assert.Equal(t, 0, broker.dials)
+	assert.NoError(t, sink.Send(context.Background(), newTestEvent()))
+	assert.Equal(t, 1, broker.dials)
+
+	// A closed channel is opened again on the same connection
+	assert.NoError(t, sink.ch.Close())
+	assert.NoError(t, sink.Send(context.Background(), newTestEvent()))
+	assert.Equal(t, 1, broker.dials)
+	assert.Equal(t, 2, broker.channels)
+
+	// A closed connection is made again
+	assert.NoError(t, sink.conn.Close())
+	assert.NoError(t, sink.Send(context.Background(), newTestEvent()))
+	assert.Equal(t, 2, broker.dials)
+	assert.Equal(t, 3, broker.channels)
+	assert.Len(t, broker.published, 3)
+}
+
+func TestAMQPSink_DialError(t *testing.T) {
+	// The broker being down does not fail the sink, only the events
+	dials := 0
+	sink := newAMQPSink(&AMQPConfig{}, func() (amqpConnection, error) {
+		dials++
+		return nil, errors.New("connection refused")
+	})
+	defer sink.Close()
+	assert.Error(t, sink.Send(context.Background(), newTestEvent()))
+	assert.Equal(t, 2, dials)
+
+	_, err := NewAMQPSink(&AMQPConfig{URL: "amqp://127.0.0.1:1"})
+	assert.NoError(t, err)
+
+	_, err = NewAMQPSink(&AMQPConfig{})
+	assert.Error(t, err)
+
+	_, err = NewAMQPSink(&AMQPConfig{URL: "http://rabbitmq"})
+	assert.Error(t, err)
+}
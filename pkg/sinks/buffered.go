@@ -0,0 +1,107 @@
+package sinks
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	bufferedMinBackoff = time.Second
+	bufferedMaxBackoff = 30 * time.Second
+)
+
+// bufferedSender sends the items of a bounded buffer in the background. An item that cannot be sent is retried with
+// backoff until it is sent or the sender is closed, so while the destination is unreachable the items wait in the
+// buffer and only the ones that do not fit are dropped. An item that send fails with a permanentError is dropped
+// right away instead, so that it does not hold up the next ones. disconnect is called after a failed send, so that
+// the next one connects again, and when the sender stops.
+type bufferedSender struct {
+	name       string
+	send       func(item interface{}) error
+	disconnect func()
+
+	items      chan interface{}
+	stopCh     chan struct{}
+	doneCh     chan struct{}
+	minBackoff time.Duration
+}
+
+// permanentError is an error that sending the item again would not fix, e.g. the destination rejected the item itself
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+func newBufferedSender(name string, size int, send func(item interface{}) error, disconnect func()) *bufferedSender {
+	b := &bufferedSender{
+		name:       name,
+		send:       send,
+		disconnect: disconnect,
+		items:      make(chan interface{}, size),
+		stopCh:     make(chan struct{}),
+		doneCh:     make(chan struct{}),
+		minBackoff: bufferedMinBackoff,
+	}
+	go b.run()
+	return b
+}
+
+// submit puts the item in the buffer, it fails when the buffer is full
+func (b *bufferedSender) submit(item interface{}) error {
+	select {
+	case b.items <- item:
+		return nil
+	default:
+		return fmt.Errorf("%s buffer is full, the event is dropped", b.name)
+	}
+}
+
+func (b *bufferedSender) run() {
+	defer close(b.doneCh)
+	defer b.disconnect()
+
+	for item := range b.items {
+		backoff := b.minBackoff
+		for {
+			err := b.send(item)
+			if err == nil {
+				break
+			}
+			var permanent *permanentError
+			if errors.As(err, &permanent) {
+				log.Error().Err(err).Str("sink", b.name).Msg("Buffered event was rejected, the event is dropped")
+				break
+			}
+			log.Error().Err(err).Str("sink", b.name).Msg("Cannot send buffered event")
+			b.disconnect()
+
+			select {
+			case <-b.stopCh:
+				log.Warn().Str("sink", b.name).Int("events", len(b.items)+1).Msg("Sink closed, buffered events are dropped")
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > bufferedMaxBackoff {
+				backoff = bufferedMaxBackoff
+			}
+		}
+	}
+}
+
+// close sends the buffered items unless the destination cannot be reached
+func (b *bufferedSender) close() {
+	close(b.stopCh)
+	close(b.items)
+	<-b.doneCh
+}
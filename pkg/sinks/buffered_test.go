@@ -0,0 +1,58 @@
+package sinks
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferedSender_PermanentError(t *testing.T) {
+	attempts := make(map[string]int)
+	sent := make(chan string, 2)
+	sender := newBufferedSender("test", 10, func(item interface{}) error {
+		attempts[item.(string)]++
+		if item == "rejected" {
+			return &permanentError{errors.New("not authorized")}
+		}
+		sent <- item.(string)
+		return nil
+	}, func() {})
+	sender.minBackoff = time.Hour
+
+	// The rejected item is dropped instead of holding up the next one
+	assert.NoError(t, sender.submit("rejected"))
+	assert.NoError(t, sender.submit("accepted"))
+	select {
+	case item := <-sent:
+		assert.Equal(t, "accepted", item)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the item after the rejected one was not sent")
+	}
+	sender.close()
+	assert.Equal(t, map[string]int{"rejected": 1, "accepted": 1}, attempts)
+}
+
+func TestBufferedSender_Retry(t *testing.T) {
+	var attempts int
+	sent := make(chan struct{})
+	sender := newBufferedSender("test", 10, func(item interface{}) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("connection refused")
+		}
+		close(sent)
+		return nil
+	}, func() {})
+	sender.minBackoff = time.Millisecond
+
+	assert.NoError(t, sender.submit("event"))
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the item was not sent again")
+	}
+	sender.close()
+	assert.Equal(t, 3, attempts)
+}
@@ -1 +1,297 @@
 package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/resmoio/kubernetes-event-exporter/pkg/batch"
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/rs/zerolog/log"
+)
+
+// Limits of a PutLogEvents call
+const (
+	cloudWatchMaxBatchEvents = 10000
+	cloudWatchMaxBatchBytes  = 1048576
+	cloudWatchEventOverhead  = 26
+	cloudWatchMaxBatchSpan   = 24 * time.Hour
+)
+
+// CloudWatchConfig writes the events to a log group of CloudWatch Logs. The log stream is a template, the group and
+// the streams are created with the first events when they do not exist. Without the permission to create the group,
+// it is expected to exist. Endpoint overrides the endpoint of the region, e.g. for a local stand-in of CloudWatch Logs.
+type CloudWatchConfig struct {
+	Region          string                 `yaml:"region"`
+	Endpoint        string                 `yaml:"endpoint"`
+	LogGroupName    string                 `yaml:"logGroupName"`
+	LogStreamName   string                 `yaml:"logStreamName"`
+	Layout          map[string]interface{} `yaml:"layout"`
+	LayoutRef       string                 `yaml:"layoutRef"`
+	BatchSize       int                    `yaml:"batchSize"`
+	MaxRetries      int                    `yaml:"maxRetries"`
+	IntervalSeconds int                    `yaml:"intervalSeconds"`
+	TimeoutSeconds  int                    `yaml:"timeoutSeconds"`
+}
+
+type cloudWatchEntry struct {
+	stream    string
+	timestamp int64
+	message   string
+}
+
+type CloudWatchSink struct {
+	cfg         *CloudWatchConfig
+	svc         *cloudwatchlogs.CloudWatchLogs
+	batchWriter *batch.Writer
+
+	mu           sync.Mutex
+	groupCreated bool
+	streams      map[string]*string
+}
+
+func NewCloudWatchSink(cfg *CloudWatchConfig) (Sink, error) {
+	if cfg.LogGroupName == "" {
+		return nil, fmt.Errorf("cloudwatch logGroupName is required")
+	}
+	if cfg.LogStreamName == "" {
+		cfg.LogStreamName = "{{ .InvolvedObject.Namespace }}"
+	}
+
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 1000
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.IntervalSeconds == 0 {
+		cfg.IntervalSeconds = 5
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 30
+	}
+
+	awsCfg := &aws.Config{Region: aws.String(cfg.Region)}
+	if cfg.Endpoint != "" {
+		awsCfg.Endpoint = aws.String(cfg.Endpoint)
+	}
+	sess, err := session.NewSession(awsCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &CloudWatchSink{
+		cfg:     cfg,
+		svc:     cloudwatchlogs.New(sess),
+		streams: make(map[string]*string),
+	}
+
+	c.batchWriter = batch.NewWriter(
+		batch.WriterConfig{
+			BatchSize:  cfg.BatchSize,
+			MaxRetries: cfg.MaxRetries,
+			Interval:   time.Duration(cfg.IntervalSeconds) * time.Second,
+			Timeout:    time.Duration(cfg.TimeoutSeconds) * time.Second,
+		},
+		c.handleBatch,
+	)
+	c.batchWriter.Start()
+	return c, nil
+}
+
+func isAWSError(err error, code string) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == code
+}
+
+func (c *CloudWatchSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	message, err := serializeEventWithLayout(c.cfg.Layout, ev)
+	if err != nil {
+		return err
+	}
+
+	stream, err := GetString(ev, c.cfg.LogStreamName)
+	if err != nil {
+		return err
+	}
+	if stream == "" {
+		return fmt.Errorf("log stream name of the event is empty")
+	}
+
+	timestamp := ev.GetTimestampMs()
+	if ev.FirstTimestamp.IsZero() && ev.EventTime.IsZero() {
+		timestamp = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+
+	c.batchWriter.Submit(&cloudWatchEntry{stream: stream, timestamp: timestamp, message: string(message)})
+	return nil
+}
+
+// handleBatch puts the events of each stream, a failed stream is retried with the next batch
+func (c *CloudWatchSink) handleBatch(ctx context.Context, items []interface{}) []bool {
+	res := make([]bool, len(items))
+	byStream := make(map[string][]int)
+	for i, item := range items {
+		entry := item.(*cloudWatchEntry)
+		byStream[entry.stream] = append(byStream[entry.stream], i)
+	}
+
+	for stream, indexes := range byStream {
+		entries := make([]*cloudWatchEntry, len(indexes))
+		for i, idx := range indexes {
+			entries[i] = items[idx].(*cloudWatchEntry)
+		}
+
+		err := c.putStream(ctx, stream, entries)
+		if err != nil {
+			log.Error().Err(err).Str("logStream", stream).Int("events", len(entries)).
+				Msg("Cannot put events to CloudWatch Logs")
+		}
+		for _, idx := range indexes {
+			res[idx] = err == nil
+		}
+	}
+	return res
+}
+
+// putStream puts the events of a stream in chronological order, in as many calls as the limits need
+func (c *CloudWatchSink) putStream(ctx context.Context, stream string, entries []*cloudWatchEntry) error {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].timestamp < entries[j].timestamp
+	})
+
+	for _, chunk := range chunkCloudWatchEntries(entries) {
+		events := make([]*cloudwatchlogs.InputLogEvent, len(chunk))
+		for i, entry := range chunk {
+			events[i] = &cloudwatchlogs.InputLogEvent{
+				Message:   aws.String(entry.message),
+				Timestamp: aws.Int64(entry.timestamp),
+			}
+		}
+		if err := c.putLogEvents(ctx, stream, events); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunkCloudWatchEntries splits the sorted entries by the count, size and time span limits of a call
+func chunkCloudWatchEntries(entries []*cloudWatchEntry) [][]*cloudWatchEntry {
+	var chunks [][]*cloudWatchEntry
+	start, size := 0, 0
+	for i, entry := range entries {
+		entrySize := len(entry.message) + cloudWatchEventOverhead
+		span := time.Duration(entry.timestamp-entries[start].timestamp) * time.Millisecond
+		if i > start && (i-start >= cloudWatchMaxBatchEvents || size+entrySize > cloudWatchMaxBatchBytes ||
+			span >= cloudWatchMaxBatchSpan) {
+			chunks = append(chunks, entries[start:i])
+			start, size = i, 0
+		}
+		size += entrySize
+	}
+	if start < len(entries) {
+		chunks = append(chunks, entries[start:])
+	}
+	return chunks
+}
+
+// createLogGroup creates the log group before the first events are put. A group that exists or that cannot be created
+// for lack of permission, e.g. when it is managed outside of the exporter, is used as it is.
+func (c *CloudWatchSink) createLogGroup(ctx context.Context) error {
+	c.mu.Lock()
+	created := c.groupCreated
+	c.mu.Unlock()
+	if created {
+		return nil
+	}
+
+	_, err := c.svc.CreateLogGroupWithContext(ctx, &cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: aws.String(c.cfg.LogGroupName),
+	})
+	switch {
+	case err == nil, isAWSError(err, cloudwatchlogs.ErrCodeResourceAlreadyExistsException):
+	case isAWSError(err, "AccessDeniedException"):
+		log.Warn().Err(err).Str("logGroup", c.cfg.LogGroupName).
+			Msg("Cannot create the CloudWatch Logs group, it is expected to exist")
+	default:
+		return fmt.Errorf("cannot create log group %s: %w", c.cfg.LogGroupName, err)
+	}
+
+	c.mu.Lock()
+	c.groupCreated = true
+	c.mu.Unlock()
+	return nil
+}
+
+// putLogEvents creates the stream when it does not exist and uses the sequence token that CloudWatch expects.
+// Throttled calls are retried with backoff by the AWS client, then the batch writer retries them.
+func (c *CloudWatchSink) putLogEvents(ctx context.Context, stream string, events []*cloudwatchlogs.InputLogEvent) error {
+	if err := c.createLogGroup(ctx); err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		c.mu.Lock()
+		token, known := c.streams[stream]
+		c.mu.Unlock()
+
+		if !known {
+			_, err := c.svc.CreateLogStreamWithContext(ctx, &cloudwatchlogs.CreateLogStreamInput{
+				LogGroupName:  aws.String(c.cfg.LogGroupName),
+				LogStreamName: aws.String(stream),
+			})
+			if err != nil && !isAWSError(err, cloudwatchlogs.ErrCodeResourceAlreadyExistsException) {
+				return fmt.Errorf("cannot create log stream: %w", err)
+			}
+		}
+
+		out, err := c.svc.PutLogEventsWithContext(ctx, &cloudwatchlogs.PutLogEventsInput{
+			LogGroupName:  aws.String(c.cfg.LogGroupName),
+			LogStreamName: aws.String(stream),
+			LogEvents:     events,
+			SequenceToken: token,
+		}, request.WithResponseReadTimeout(time.Duration(c.cfg.TimeoutSeconds)*time.Second))
+
+		var tokenErr *cloudwatchlogs.InvalidSequenceTokenException
+		switch {
+		case err == nil:
+			c.setToken(stream, out.NextSequenceToken)
+			if info := out.RejectedLogEventsInfo; info != nil {
+				log.Warn().Str("logStream", stream).Interface("rejected", info).
+					Msg("CloudWatch Logs rejected some events")
+			}
+			return nil
+		case isAWSError(err, cloudwatchlogs.ErrCodeDataAlreadyAcceptedException):
+			return nil
+		case attempt > 0:
+			return err
+		case errors.As(err, &tokenErr):
+			c.setToken(stream, tokenErr.ExpectedSequenceToken)
+		case isAWSError(err, cloudwatchlogs.ErrCodeResourceNotFoundException):
+			// The stream was deleted, it is created again
+			c.mu.Lock()
+			delete(c.streams, stream)
+			c.mu.Unlock()
+		default:
+			return err
+		}
+	}
+}
+
+func (c *CloudWatchSink) setToken(stream string, token *string) {
+	c.mu.Lock()
+	c.streams[stream] = token
+	c.mu.Unlock()
+}
+
+func (c *CloudWatchSink) Close() {
+	c.batchWriter.Stop()
+}
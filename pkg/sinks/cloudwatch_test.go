@@ -0,0 +1,156 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type cloudWatchCall struct {
+	operation string
+	body      map[string]interface{}
+}
+
+// newCloudWatchServer is a stand-in of CloudWatch Logs that expects the sequence token t1 for the first put. The
+// log group cannot be created with the error of groupError.
+func newCloudWatchServer(t *testing.T, calls *[]cloudWatchCall, groupError string) *httptest.Server {
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		operation := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "Logs_20140328.")
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		*calls = append(*calls, cloudWatchCall{operation: operation, body: body})
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		switch operation {
+		case "CreateLogGroup":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"` + groupError + `","message":"The log group cannot be created"}`))
+		case "CreateLogStream":
+			w.Write([]byte(`{}`))
+		case "PutLogEvents":
+			if body["sequenceToken"] == nil {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"__type":"InvalidSequenceTokenException","expectedSequenceToken":"t1","message":"invalid"}`))
+				return
+			}
+			w.Write([]byte(`{"nextSequenceToken":"t2"}`))
+		}
+	}))
+}
+
+func TestCloudWatchSink_Send(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	var calls []cloudWatchCall
+	server := newCloudWatchServer(t, &calls, "ResourceAlreadyExistsException")
+	defer server.Close()
+
+	sink, err := NewCloudWatchSink(&CloudWatchConfig{
+		Region:       "us-east-1",
+		Endpoint:     server.URL,
+		LogGroupName: "events",
+		Layout:       map[string]interface{}{"reason": "{{ .Reason }}"},
+	})
+	assert.NoError(t, err)
+
+	for i, reason := range []string{"Second", "First"} {
+		ev := newTestEvent()
+		ev.Reason = reason
+		ev.FirstTimestamp = metav1.NewTime(time.UnixMilli(1600000000000 - int64(i)))
+		assert.NoError(t, sink.Send(context.Background(), ev))
+	}
+	sink.Close()
+
+	operations := make([]string, len(calls))
+	for i, call := range calls {
+		operations[i] = call.operation
+	}
+	assert.Equal(t, []string{"CreateLogGroup", "CreateLogStream", "PutLogEvents", "PutLogEvents"}, operations)
+	assert.Equal(t, "default", calls[1].body["logStreamName"])
+
+	put := calls[3].body
+	assert.Equal(t, "events", put["logGroupName"])
+	assert.Equal(t, "t1", put["sequenceToken"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"message": `{"reason":"First"}`, "timestamp": float64(1599999999999)},
+		map[string]interface{}{"message": `{"reason":"Second"}`, "timestamp": float64(1600000000000)},
+	}, put["logEvents"])
+}
+
+func TestCloudWatchSink_LogGroupAccessDenied(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	var calls []cloudWatchCall
+	server := newCloudWatchServer(t, &calls, "AccessDeniedException")
+	defer server.Close()
+
+	// Nothing is called before the first events
+	sink, err := NewCloudWatchSink(&CloudWatchConfig{
+		Region:       "us-east-1",
+		Endpoint:     server.URL,
+		LogGroupName: "events",
+		MaxRetries:   1,
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, calls)
+
+	// Without the permission to create it, the group is expected to exist
+	assert.NoError(t, sink.Send(context.Background(), newTestEvent()))
+	sink.Close()
+
+	operations := make([]string, len(calls))
+	for i, call := range calls {
+		operations[i] = call.operation
+	}
+	assert.Equal(t, []string{"CreateLogGroup", "CreateLogStream", "PutLogEvents", "PutLogEvents"}, operations)
+
+	// Another error stops the events
+	calls = nil
+	server = newCloudWatchServer(t, &calls, "InvalidParameterException")
+	defer server.Close()
+	sink, err = NewCloudWatchSink(&CloudWatchConfig{
+		Region:       "us-east-1",
+		Endpoint:     server.URL,
+		LogGroupName: "events",
+		MaxRetries:   1,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Send(context.Background(), newTestEvent()))
+	sink.Close()
+	assert.Equal(t, "CreateLogGroup", calls[len(calls)-1].operation)
+}
+
+func TestChunkCloudWatchEntries(t *testing.T) {
+	var entries []*cloudWatchEntry
+	for i := 0; i < cloudWatchMaxBatchEvents+1; i++ {
+		entries = append(entries, &cloudWatchEntry{timestamp: 1, message: "x"})
+	}
+	chunks := chunkCloudWatchEntries(entries)
+	assert.Len(t, chunks, 2)
+	assert.Len(t, chunks[0], cloudWatchMaxBatchEvents)
+
+	big := strings.Repeat("x", cloudWatchMaxBatchBytes/2)
+	chunks = chunkCloudWatchEntries([]*cloudWatchEntry{{message: big}, {message: big}, {message: "x"}})
+	assert.Len(t, chunks, 2)
+	assert.Len(t, chunks[0], 1)
+	assert.Len(t, chunks[1], 2)
+
+	day := int64(24 * time.Hour / time.Millisecond)
+	chunks = chunkCloudWatchEntries([]*cloudWatchEntry{{timestamp: 0}, {timestamp: day - 1}, {timestamp: day}})
+	assert.Len(t, chunks, 2)
+	assert.Len(t, chunks[0], 2)
+}
@@ -33,6 +33,7 @@ type ElasticsearchConfig struct {
 	Type        string                 `yaml:"type"`
 	TLS         TLS                    `yaml:"tls"`
 	Layout      map[string]interface{} `yaml:"layout"`
+	LayoutRef   string                 `yaml:"layoutRef"`
 }
 
 func NewElasticsearch(cfg *ElasticsearchConfig) (*Elasticsearch, error) {
@@ -1,3 +1,432 @@
 package sinks
 
-// Just kidding, please don't implement this
\ No newline at end of file
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultEmailSubject = "[{{ .Type }}] {{ .Reason }} on {{ objectRef . }}"
+	defaultEmailText    = "{{ .Message }}\n\nObject: {{ objectRef . }}\nReason: {{ .Reason }}\nSeverity: {{ .Severity }}\n" +
+		"Count: {{ .Count }}\n{{ if .ClusterName }}Cluster: {{ .ClusterName }}\n{{ end }}"
+
+	defaultDigestSubject = "{{ .Count }} Kubernetes events"
+	defaultDigestText    = "{{ range .Events }}[{{ .Type }}] {{ .Reason }} on {{ objectRef . }}: {{ .Message }}\n{{ end }}" +
+		"{{ if gt .Count (len .Events) }}... and {{ sub .Count (len .Events) }} more events\n{{ end }}"
+)
+
+// EmailConfig sends the events by email over SMTP. Security is starttls, tls for implicit TLS or none. The
+// recipients, the subject and the text and HTML bodies are templates; the HTML bodies are HTML templates, which escape
+// the values. With digest, the events are collected during the window and every group of recipients
+// gets one email with its events.
+type EmailConfig struct {
+	Host     string             `yaml:"host"`
+	Port     int                `yaml:"port"`
+	Security string             `yaml:"security"`
+	Username string             `yaml:"username"`
+	Password string             `yaml:"password"`
+	From     string             `yaml:"from"`
+	To       []string           `yaml:"to"`
+	Subject  string             `yaml:"subject"`
+	Text     string             `yaml:"text"`
+	HTML     string             `yaml:"html"`
+	Digest   *EmailDigestConfig `yaml:"digest"`
+	TLS      TLS                `yaml:"tls"`
+}
+
+// EmailDigestConfig renders the templates of the digest with the recipients, the events, their count and the
+// window. At most MaxEvents events are kept in a digest, the count includes the others.
+type EmailDigestConfig struct {
+	WindowSeconds int    `yaml:"windowSeconds"`
+	MaxEvents     int    `yaml:"maxEvents"`
+	Subject       string `yaml:"subject"`
+	Text          string `yaml:"text"`
+	HTML          string `yaml:"html"`
+}
+
+// Validate rejects a negative window or event limit, unset they default to an hour and 100 events
+func (c *EmailDigestConfig) Validate() error {
+	if c.WindowSeconds < 0 {
+		return fmt.Errorf("email digest windowSeconds must be positive, not %d", c.WindowSeconds)
+	}
+	if c.MaxEvents < 0 {
+		return fmt.Errorf("email digest maxEvents must be positive, not %d", c.MaxEvents)
+	}
+	return nil
+}
+
+// emailDigest is what the digest templates are rendered with
+type emailDigest struct {
+	To     []string
+	Events []*kube.EnhancedEvent
+	Count  int
+	Start  time.Time
+	End    time.Time
+}
+
+type EmailSink struct {
+	cfg       *EmailConfig
+	tlsConfig *tls.Config
+	now       func() time.Time
+
+	mu      sync.Mutex
+	digests map[string]*emailDigest
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+func NewEmailSink(cfg *EmailConfig) (Sink, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("email host is required")
+	}
+	if cfg.From == "" || len(cfg.To) == 0 {
+		return nil, fmt.Errorf("email from and to are required")
+	}
+
+	switch cfg.Security {
+	case "":
+		cfg.Security = "starttls"
+	case "starttls", "tls", "none":
+	default:
+		return nil, fmt.Errorf("email security must be starttls, tls or none, not %q", cfg.Security)
+	}
+	if cfg.Port == 0 {
+		switch cfg.Security {
+		case "starttls":
+			cfg.Port = 587
+		case "tls":
+			cfg.Port = 465
+		default:
+			cfg.Port = 25
+		}
+	}
+	if cfg.Subject == "" {
+		cfg.Subject = defaultEmailSubject
+	}
+	if cfg.Text == "" && cfg.HTML == "" {
+		cfg.Text = defaultEmailText
+	}
+
+	tlsConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = cfg.Host
+	}
+
+	e := &EmailSink{
+		cfg:       cfg,
+		tlsConfig: tlsConfig,
+		now:       time.Now,
+	}
+
+	if d := cfg.Digest; d != nil {
+		if err := d.Validate(); err != nil {
+			return nil, err
+		}
+		if d.WindowSeconds == 0 {
+			d.WindowSeconds = 3600
+		}
+		if d.MaxEvents == 0 {
+			d.MaxEvents = 100
+		}
+		if d.Subject == "" {
+			d.Subject = defaultDigestSubject
+		}
+		if d.Text == "" && d.HTML == "" {
+			d.Text = defaultDigestText
+		}
+		e.digests = make(map[string]*emailDigest)
+		e.stopCh = make(chan struct{})
+		e.doneCh = make(chan struct{})
+		go e.run(time.Duration(d.WindowSeconds) * time.Second)
+	}
+	return e, nil
+}
+
+func (e *EmailSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	to, err := e.recipients(ev)
+	if err != nil {
+		return err
+	}
+
+	if e.cfg.Digest != nil {
+		e.collect(to, ev)
+		return nil
+	}
+
+	subject, err := GetString(ev, e.cfg.Subject)
+	if err != nil {
+		return err
+	}
+	var text, html string
+	if e.cfg.Text != "" {
+		if text, err = GetString(ev, e.cfg.Text); err != nil {
+			return err
+		}
+	}
+	if e.cfg.HTML != "" {
+		if html, err = executeHTMLTemplate(ev, e.cfg.HTML); err != nil {
+			return err
+		}
+	}
+	return e.send(to, subject, text, html)
+}
+
+// recipients renders the recipients of the event, the sorted list is also its recipient group
+func (e *EmailSink) recipients(ev *kube.EnhancedEvent) ([]string, error) {
+	seen := make(map[string]bool)
+	to := make([]string, 0, len(e.cfg.To))
+	for _, text := range e.cfg.To {
+		address, err := GetString(ev, text)
+		if err != nil {
+			return nil, err
+		}
+		address = strings.TrimSpace(address)
+		if address != "" && !seen[address] {
+			seen[address] = true
+			to = append(to, address)
+		}
+	}
+	if len(to) == 0 {
+		return nil, fmt.Errorf("the event has no email recipients")
+	}
+	sort.Strings(to)
+	return to, nil
+}
+
+func (e *EmailSink) collect(to []string, ev *kube.EnhancedEvent) {
+	key := strings.Join(to, ",")
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	digest, ok := e.digests[key]
+	if !ok {
+		digest = &emailDigest{To: to, Start: e.now()}
+		e.digests[key] = digest
+	}
+	digest.Count++
+	if len(digest.Events) < e.cfg.Digest.MaxEvents {
+		digest.Events = append(digest.Events, ev)
+	}
+}
+
+func (e *EmailSink) run(window time.Duration) {
+	defer close(e.doneCh)
+	ticker := time.NewTicker(window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.flush()
+		case <-e.stopCh:
+			e.flush()
+			return
+		}
+	}
+}
+
+// flush sends a digest to every recipient group that has events
+func (e *EmailSink) flush() {
+	e.mu.Lock()
+	digests := e.digests
+	e.digests = make(map[string]*emailDigest)
+	e.mu.Unlock()
+
+	for key, digest := range digests {
+		digest.End = e.now()
+		if err := e.sendDigest(digest); err != nil {
+			log.Error().Err(err).Str("to", key).Int("events", digest.Count).Msg("Cannot send email digest")
+		}
+	}
+}
+
+func (e *EmailSink) sendDigest(digest *emailDigest) error {
+	texts := []string{e.cfg.Digest.Subject, e.cfg.Digest.Text}
+	rendered := make([]string, len(texts))
+	for i, text := range texts {
+		if text == "" {
+			continue
+		}
+		tmpl, err := parseTemplate(text)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, digest); err != nil {
+			return err
+		}
+		rendered[i] = strings.ReplaceAll(buf.String(), rawMarker, "")
+	}
+
+	var html string
+	if e.cfg.Digest.HTML != "" {
+		var err error
+		if html, err = executeHTMLTemplate(digest, e.cfg.Digest.HTML); err != nil {
+			return err
+		}
+	}
+	return e.send(digest.To, rendered[0], rendered[1], html)
+}
+
+// send delivers one email with a text body, an HTML body or both as alternatives
+func (e *EmailSink) send(to []string, subject, text, html string) error {
+	msg, err := e.buildMessage(to, subject, text, html)
+	if err != nil {
+		return err
+	}
+
+	client, err := e.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if e.cfg.Security == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server %s does not support STARTTLS", e.cfg.Host)
+		}
+		if err := client.StartTLS(e.tlsConfig); err != nil {
+			return err
+		}
+	}
+	if e.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(e.cfg.From); err != nil {
+		return err
+	}
+	for _, address := range to {
+		if err := client.Rcpt(address); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (e *EmailSink) dial() (*smtp.Client, error) {
+	address := net.JoinHostPort(e.cfg.Host, strconv.Itoa(e.cfg.Port))
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	var conn net.Conn
+	var err error
+	if e.cfg.Security == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, e.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := smtp.NewClient(conn, e.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+func (e *EmailSink) buildMessage(to []string, subject, text, html string) ([]byte, error) {
+	var buf bytes.Buffer
+	header := textproto.MIMEHeader{}
+	header.Set("From", e.cfg.From)
+	header.Set("To", strings.Join(to, ", "))
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header.Set("Date", e.now().Format(time.RFC1123Z))
+	header.Set("MIME-Version", "1.0")
+
+	if text != "" && html != "" {
+		mw := multipart.NewWriter(&buf)
+		header.Set("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+		for _, part := range []struct{ contentType, body string }{
+			{"text/plain", text},
+			{"text/html", html},
+		} {
+			pw, err := mw.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {part.contentType + "; charset=utf-8"},
+				"Content-Transfer-Encoding": {"quoted-printable"},
+			})
+			if err != nil {
+				return nil, err
+			}
+			if err := writeQuotedPrintable(pw, part.body); err != nil {
+				return nil, err
+			}
+		}
+		if err := mw.Close(); err != nil {
+			return nil, err
+		}
+	} else {
+		contentType, body := "text/plain", text
+		if html != "" {
+			contentType, body = "text/html", html
+		}
+		header.Set("Content-Type", contentType+"; charset=utf-8")
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		if err := writeQuotedPrintable(&buf, body); err != nil {
+			return nil, err
+		}
+	}
+
+	var msg bytes.Buffer
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&msg, "%s: %s\r\n", k, header.Get(k))
+	}
+	msg.WriteString("\r\n")
+	msg.Write(buf.Bytes())
+	return msg.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qw := quotedprintable.NewWriter(w)
+	if _, err := qw.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+// Close sends the pending digests
+func (e *EmailSink) Close() {
+	if e.stopCh != nil {
+		close(e.stopCh)
+		<-e.doneCh
+	}
+}
@@ -0,0 +1,245 @@
+package sinks
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type smtpMessage struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// smtpServer is a local SMTP stand-in that accepts every message and AUTH PLAIN
+type smtpServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []smtpMessage
+	wg       sync.WaitGroup
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := &smtpServer{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.wg.Add(1)
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *smtpServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	var msg smtpMessage
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			creds, _ := base64.StdEncoding.DecodeString(strings.Fields(line)[2])
+			msg.auth = string(creds)
+			reply("235 Authentication successful")
+		case "MAIL":
+			msg.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+			reply("250 OK")
+		case "RCPT":
+			msg.to = append(msg.to, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil || l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			msg.data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = smtpMessage{}
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *smtpServer) received() []smtpMessage {
+	s.listener.Close()
+	s.wg.Wait()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.messages
+}
+
+func TestEmailSink_Send(t *testing.T) {
+	server := newSMTPServer(t)
+	sink, err := NewEmailSink(&EmailConfig{
+		Host:     "127.0.0.1",
+		Port:     server.port(),
+		Security: "none",
+		Username: "exporter",
+		Password: "secret",
+		From:     "exporter@example.com",
+		To:       []string{"{{ .InvolvedObject.Namespace }}@example.com", "ops@example.com"},
+		Subject:  "{{ .Reason }} ünder {{ .InvolvedObject.Name }}",
+		Text:     "{{ .Message }}",
+		HTML:     "<b>{{ .Message }}</b>",
+	})
+	assert.NoError(t, err)
+	ev := newTestEvent()
+	ev.Message = "Back-off restarting <failed> container"
+	assert.NoError(t, sink.Send(context.Background(), ev))
+	sink.Close()
+
+	messages := server.received()
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "\x00exporter\x00secret", messages[0].auth)
+	assert.Equal(t, "exporter@example.com", messages[0].from)
+	assert.Equal(t, []string{"default@example.com", "ops@example.com"}, messages[0].to)
+
+	m, err := mail.ReadMessage(strings.NewReader(messages[0].data))
+	assert.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	assert.NoError(t, err)
+	assert.Equal(t, "BackOff ünder nginx", subject)
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		body, err := ioutil.ReadAll(quotedprintable.NewReader(part))
+		assert.NoError(t, err)
+		bodies = append(bodies, string(body))
+	}
+	assert.Equal(t, []string{
+		"Back-off restarting <failed> container",
+		"<b>Back-off restarting &lt;failed&gt; container</b>",
+	}, bodies)
+}
+
+func TestEmailSink_Digest(t *testing.T) {
+	server := newSMTPServer(t)
+	sink, err := NewEmailSink(&EmailConfig{
+		Host:     "127.0.0.1",
+		Port:     server.port(),
+		Security: "none",
+		From:     "exporter@example.com",
+		To:       []string{"{{ .InvolvedObject.Namespace }}@example.com"},
+		Digest:   &EmailDigestConfig{MaxEvents: 2},
+	})
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		ev := newTestEvent()
+		ev.Reason = "BackOff" + strconv.Itoa(i)
+		assert.NoError(t, sink.Send(context.Background(), ev))
+	}
+	ev := newTestEvent()
+	ev.Reason = "Killing"
+	ev.InvolvedObject.Namespace = "kube-system"
+	assert.NoError(t, sink.Send(context.Background(), ev))
+	sink.Close()
+
+	bodies := make(map[string]string)
+	subjects := make(map[string]string)
+	for _, msg := range server.received() {
+		assert.Len(t, msg.to, 1)
+		m, err := mail.ReadMessage(strings.NewReader(msg.data))
+		assert.NoError(t, err)
+		body, err := ioutil.ReadAll(quotedprintable.NewReader(m.Body))
+		assert.NoError(t, err)
+		bodies[msg.to[0]] = strings.ReplaceAll(string(body), "\r\n", "\n")
+		subjects[msg.to[0]] = m.Header.Get("Subject")
+	}
+
+	assert.Equal(t, map[string]string{
+		"default@example.com":     "3 Kubernetes events",
+		"kube-system@example.com": "1 Kubernetes events",
+	}, subjects)
+	assert.Equal(t, "[Warning] BackOff0 on Pod/default/nginx: Back-off restarting failed container\n"+
+		"[Warning] BackOff1 on Pod/default/nginx: Back-off restarting failed container\n"+
+		"... and 1 more events\n", bodies["default@example.com"])
+}
+
+func TestEmailSink_Invalid(t *testing.T) {
+	_, err := NewEmailSink(&EmailConfig{From: "a@example.com", To: []string{"b@example.com"}})
+	assert.Error(t, err)
+
+	_, err = NewEmailSink(&EmailConfig{Host: "smtp", To: []string{"b@example.com"}})
+	assert.Error(t, err)
+
+	_, err = NewEmailSink(&EmailConfig{Host: "smtp", From: "a@example.com", To: []string{"b@example.com"}, Security: "ssl"})
+	assert.Error(t, err)
+}
+
+func TestEmailSink_InvalidDigest(t *testing.T) {
+	r := ReceiverConfig{
+		Name: "email",
+		Email: &EmailConfig{
+			Host:   "smtp",
+			From:   "a@example.com",
+			To:     []string{"b@example.com"},
+			Digest: &EmailDigestConfig{WindowSeconds: -60},
+		},
+	}
+	assert.Error(t, r.Validate())
+
+	_, err := NewEmailSink(r.Email)
+	assert.Error(t, err)
+
+	r.Email.Digest.WindowSeconds = 0
+	assert.NoError(t, r.Validate())
+}
@@ -12,6 +12,7 @@ import (
 type FileConfig struct {
 	Path       string                 `yaml:"path"`
 	Layout     map[string]interface{} `yaml:"layout"`
+	LayoutRef  string                 `yaml:"layoutRef"`
 	MaxSize    int                    `yaml:"maxsize"`
 	MaxAge     int                    `yaml:"maxage"`
 	MaxBackups int                    `yaml:"maxbackups"`
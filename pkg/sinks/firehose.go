@@ -14,6 +14,7 @@ type FirehoseConfig struct {
 	DeliveryStreamName string                 `yaml:"deliveryStreamName"`
 	Region             string                 `yaml:"region"`
 	Layout             map[string]interface{} `yaml:"layout"`
+	LayoutRef          string                 `yaml:"layoutRef"`
 	// DeDot all labels and annotations in the event. For both the event and the involvedObject
 	DeDot bool `yaml:"deDot"`
 }
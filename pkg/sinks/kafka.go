@@ -16,6 +16,7 @@ type KafkaConfig struct {
 	Topic            string                 `yaml:"topic"`
 	Brokers          []string               `yaml:"brokers"`
 	Layout           map[string]interface{} `yaml:"layout"`
+	LayoutRef        string                 `yaml:"layoutRef"`
 	ClientId         string                 `yaml:"clientId"`
 	CompressionCodec string                 `yaml:"compressionCodec" default:"none"`
 	TLS              struct {
@@ -13,6 +13,7 @@ type KinesisConfig struct {
 	StreamName string                 `yaml:"streamName"`
 	Region     string                 `yaml:"region"`
 	Layout     map[string]interface{} `yaml:"layout"`
+	LayoutRef  string                 `yaml:"layoutRef"`
 }
 
 type KinesisSink struct {
@@ -0,0 +1,155 @@
+package sinks
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// builtinLayouts are the layouts that can be referenced without defining them in the config
+var builtinLayouts = map[string]map[string]interface{}{
+	// compact is a short summary of the event for log pipelines
+	"compact": {
+		"time":    "{{ .GetTimestampISO8601 }}",
+		"type":    "{{ .Type }}",
+		"reason":  "{{ .Reason }}",
+		"object":  "{{ objectRef . }}",
+		"count":   "{{ toRaw .Count }}",
+		"message": "{{ .Message }}",
+	},
+	// ecs follows the Elastic Common Schema
+	"ecs": {
+		"@timestamp": "{{ .GetTimestampISO8601 }}",
+		"message":    "{{ .Message }}",
+		"ecs":        map[string]interface{}{"version": "8.11.0"},
+		"log":        map[string]interface{}{"level": "{{ severity . }}"},
+		"event": map[string]interface{}{
+			"kind":     "event",
+			"dataset":  "kubernetes.event",
+			"action":   "{{ .Reason }}",
+			"provider": "{{ .Source.Component }}",
+		},
+		"host": map[string]interface{}{"hostname": "{{ .Source.Host }}"},
+		"orchestrator": map[string]interface{}{
+			"type":      "kubernetes",
+			"namespace": "{{ .InvolvedObject.Namespace }}",
+			"cluster":   map[string]interface{}{"name": "{{ .ClusterName }}"},
+			"resource": map[string]interface{}{
+				"type": "{{ .InvolvedObject.Kind | lower }}",
+				"name": "{{ .InvolvedObject.Name }}",
+			},
+		},
+		"kubernetes": map[string]interface{}{
+			"event": map[string]interface{}{
+				"type":  "{{ .Type }}",
+				"count": "{{ toRaw .Count }}",
+			},
+			"labels": "{{ toRaw .InvolvedObject.Labels }}",
+		},
+	},
+	// otel follows the OpenTelemetry log data model with the Kubernetes semantic conventions
+	"otel": {
+		"timestamp":      "{{ .GetTimestampISO8601 }}",
+		"severityText":   "{{ if eq .Type \"Warning\" }}WARN{{ else }}INFO{{ end }}",
+		"severityNumber": "{{ if eq .Type \"Warning\" }}{{ toRaw 13 }}{{ else }}{{ toRaw 9 }}{{ end }}",
+		"body":           "{{ .Message }}",
+		"resource": map[string]interface{}{
+			"k8s.cluster.name":   "{{ .ClusterName }}",
+			"k8s.namespace.name": "{{ .InvolvedObject.Namespace }}",
+		},
+		"attributes": map[string]interface{}{
+			"event.name":         "{{ .Reason }}",
+			"k8s.event.uid":      "{{ .UID }}",
+			"k8s.event.count":    "{{ toRaw .Count }}",
+			"k8s.object.kind":    "{{ .InvolvedObject.Kind }}",
+			"k8s.object.name":    "{{ .InvolvedObject.Name }}",
+			"k8s.object.uid":     "{{ .InvolvedObject.UID }}",
+			"k8s.event.reporter": "{{ .Source.Component }}",
+		},
+	},
+	// cloudevents is the structured JSON format of CloudEvents 1.0 with the event as data
+	"cloudevents": {
+		"specversion":     "1.0",
+		"id":              "{{ .UID }}",
+		"source":          "{{ if .ClusterName }}/clusters/{{ .ClusterName }}{{ end }}/namespaces/{{ .InvolvedObject.Namespace }}",
+		"type":            "io.k8s.event.{{ .Reason }}",
+		"subject":         "{{ objectRef . }}",
+		"time":            "{{ .GetTimestampISO8601 }}",
+		"datacontenttype": "application/json",
+		"data":            "{{ toRaw . }}",
+	},
+}
+
+// layouts holds the layouts defined at the top level of the config, which take precedence over the built-in ones
+var layouts = struct {
+	sync.RWMutex
+	m map[string]map[string]interface{}
+}{}
+
+// SetLayouts sets the layouts that receivers can use with layoutRef
+func SetLayouts(m map[string]map[string]interface{}) {
+	layouts.Lock()
+	defer layouts.Unlock()
+	layouts.m = m
+}
+
+func getLayout(name string) (map[string]interface{}, bool) {
+	layouts.RLock()
+	defer layouts.RUnlock()
+	if layout, ok := layouts.m[name]; ok {
+		return layout, true
+	}
+	layout, ok := builtinLayouts[name]
+	return layout, ok
+}
+
+func layoutNames() []string {
+	layouts.RLock()
+	defer layouts.RUnlock()
+	names := make([]string, 0, len(builtinLayouts)+len(layouts.m))
+	for name := range builtinLayouts {
+		names = append(names, name)
+	}
+	for name := range layouts.m {
+		if _, ok := builtinLayouts[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveLayoutRefs replaces the layoutRef of the sink configs of a receiver with the layout it refers to. The sink
+// configs with a layout have both a Layout and a LayoutRef field.
+func resolveLayoutRefs(r *ReceiverConfig) error {
+	v := reflect.ValueOf(r).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() != reflect.Ptr || field.IsNil() || field.Elem().Kind() != reflect.Struct {
+			continue
+		}
+
+		ref := field.Elem().FieldByName("LayoutRef")
+		layout := field.Elem().FieldByName("Layout")
+		if !ref.IsValid() || !layout.IsValid() || ref.String() == "" {
+			continue
+		}
+
+		path := r.Name + "." + yamlFieldName(t.Field(i))
+		named, ok := getLayout(ref.String())
+		if !ok {
+			return fmt.Errorf("%s: layout %q is not defined, the layouts are %s", path, ref.String(),
+				strings.Join(layoutNames(), ", "))
+		}
+		// The layout is already set if the config was validated before
+		namedValue := reflect.ValueOf(named)
+		if layout.Len() > 0 && layout.Pointer() != namedValue.Pointer() {
+			return fmt.Errorf("%s: layout and layoutRef cannot be used together", path)
+		}
+		layout.Set(namedValue)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package sinks
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveLayoutRefs(t *testing.T) {
+	SetLayouts(map[string]map[string]interface{}{
+		"short": {"msg": "{{ .Message }}"},
+	})
+	defer SetLayouts(nil)
+
+	r := &ReceiverConfig{
+		Name:    "pipeline",
+		Webhook: &WebhookConfig{LayoutRef: "short"},
+		Kafka:   &KafkaConfig{LayoutRef: "ecs"},
+		Stdout:  &StdoutConfig{},
+	}
+	require.NoError(t, r.Validate())
+	require.Equal(t, "{{ .Message }}", r.Webhook.Layout["msg"])
+	require.Equal(t, builtinLayouts["ecs"], r.Kafka.Layout)
+	require.Nil(t, r.Stdout.Layout)
+
+	// Validating again keeps the resolved layout
+	require.NoError(t, r.Validate())
+
+	r.Webhook.Layout = map[string]interface{}{"other": "{{ .Reason }}"}
+	require.ErrorContains(t, r.Validate(), "pipeline.webhook: layout and layoutRef cannot be used together")
+
+	r.Webhook = &WebhookConfig{LayoutRef: "nope"}
+	require.ErrorContains(t, r.Validate(), `pipeline.webhook: layout "nope" is not defined`)
+}
+
+func TestBuiltinLayouts(t *testing.T) {
+	ev := &kube.EnhancedEvent{}
+	ev.UID = "1234"
+	ev.Type = "Warning"
+	ev.Reason = "BackOff"
+	ev.Count = 3
+	ev.Message = "Back-off restarting failed container"
+	ev.ClusterName = "prod"
+	ev.InvolvedObject.Kind = "Pod"
+	ev.InvolvedObject.Namespace = "default"
+	ev.InvolvedObject.Name = "nginx"
+
+	for name, layout := range builtinLayouts {
+		r := &ReceiverConfig{Name: name, SQS: &SQSConfig{LayoutRef: name}}
+		require.NoError(t, r.Validate(), name)
+
+		b, err := serializeEventWithLayout(layout, ev)
+		require.NoError(t, err, name)
+
+		var res map[string]interface{}
+		require.NoError(t, json.Unmarshal(b, &res), name)
+		require.NotEmpty(t, res, name)
+	}
+
+	res, err := convertLayoutTemplate(builtinLayouts["otel"], ev)
+	require.NoError(t, err)
+	require.Equal(t, int64(13), res["severityNumber"])
+	require.Equal(t, "WARN", res["severityText"])
+
+	res, err = convertLayoutTemplate(builtinLayouts["cloudevents"], ev)
+	require.NoError(t, err)
+	require.Equal(t, "/clusters/prod/namespaces/default", res["source"])
+	require.Equal(t, "io.k8s.event.BackOff", res["type"])
+	require.Equal(t, "Back-off restarting failed container", res["data"].(map[string]interface{})["message"])
+
+	res, err = convertLayoutTemplate(builtinLayouts["ecs"], ev)
+	require.NoError(t, err)
+	require.Equal(t, "pod", res["orchestrator"].(map[string]interface{})["resource"].(map[string]interface{})["type"])
+}
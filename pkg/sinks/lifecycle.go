@@ -0,0 +1,226 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultRecoveries are the reasons that tell a problem of the object is over
+var defaultRecoveries = map[string][]string{
+	"BackOff":            {"Pulled", "Started"},
+	"NodeNotReady":       {"NodeReady"},
+	"FailedScheduling":   {"Scheduled"},
+	"FailedAttachVolume": {"SuccessfulAttachVolume"},
+	"Unhealthy":          {"Started"},
+}
+
+// defaultQuietPeriod resolves the alerts that are not seen for a day, so the open alerts do not pile up
+const defaultQuietPeriod = 24 * time.Hour
+
+// LifecycleConfig makes an alerting receiver resolve the alerts it created. An alert is open for an object and a
+// reason, and it is resolved when the object has one of the recovery reasons of the alert reason, or when no
+// event of the alert was seen during the quiet period, a day by default. The open alerts are kept in memory only.
+type LifecycleConfig struct {
+	// Recoveries maps the reasons of the alerts to the reasons that resolve them, it replaces the default ones
+	Recoveries map[string][]string `yaml:"recoveries"`
+	// Reasons are the reasons that open alerts, by default the Warning events and the reasons with recoveries do
+	Reasons            []string `yaml:"reasons"`
+	QuietPeriodSeconds int      `yaml:"quietPeriodSeconds"`
+}
+
+// Validate rejects a negative quiet period
+func (c *LifecycleConfig) Validate() error {
+	if c.QuietPeriodSeconds < 0 {
+		return fmt.Errorf("lifecycle quietPeriodSeconds must be positive, not %d", c.QuietPeriodSeconds)
+	}
+	return nil
+}
+
+// alertSink is a sink that can resolve the alerts it creates
+type alertSink interface {
+	// openAlert creates the alert of the event and returns the ID to resolve it
+	openAlert(ctx context.Context, ev *kube.EnhancedEvent, key string) (string, error)
+	resolveAlert(ctx context.Context, id string) error
+}
+
+type openAlert struct {
+	id       string
+	lastSeen time.Time
+}
+
+// alertLifecycle tracks the alerts of a sink. Events with a recovery reason never create alerts, they only resolve
+// the open ones of their object. While an alert is open, the repeated events do not create new alerts.
+type alertLifecycle struct {
+	sink       alertSink
+	resolvedBy map[string][]string
+	opens      map[string]bool
+	warnings   bool
+	quiet      time.Duration
+	now        func() time.Time
+	mu         sync.Mutex
+	open       map[string]*openAlert
+	stopCh     chan struct{}
+	doneCh     chan struct{}
+}
+
+func newAlertLifecycle(sink alertSink, cfg *LifecycleConfig) *alertLifecycle {
+	recoveries := cfg.Recoveries
+	if recoveries == nil {
+		recoveries = defaultRecoveries
+	}
+
+	resolvedBy := make(map[string][]string)
+	for reason, recoveryReasons := range recoveries {
+		for _, recovery := range recoveryReasons {
+			resolvedBy[recovery] = append(resolvedBy[recovery], reason)
+		}
+	}
+
+	// Without reasons, Warning events open alerts and so do the reasons with recoveries, like NodeNotReady
+	opens := make(map[string]bool)
+	if len(cfg.Reasons) > 0 {
+		for _, reason := range cfg.Reasons {
+			opens[reason] = true
+		}
+	} else {
+		for reason := range recoveries {
+			opens[reason] = true
+		}
+	}
+
+	quiet := time.Duration(cfg.QuietPeriodSeconds) * time.Second
+	if quiet <= 0 {
+		quiet = defaultQuietPeriod
+	}
+
+	l := &alertLifecycle{
+		sink:       sink,
+		resolvedBy: resolvedBy,
+		opens:      opens,
+		warnings:   len(cfg.Reasons) == 0,
+		quiet:      quiet,
+		now:        time.Now,
+		open:       make(map[string]*openAlert),
+		stopCh:     make(chan struct{}),
+		doneCh:     make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// alertObject identifies the involved object, by its UID when the event has it
+func alertObject(ev *kube.EnhancedEvent) string {
+	if ev.InvolvedObject.UID != "" {
+		return string(ev.InvolvedObject.UID)
+	}
+	return ev.InvolvedObject.Kind + "/" + ev.InvolvedObject.Namespace + "/" + ev.InvolvedObject.Name
+}
+
+// opensAlert returns whether the event creates an alert when none is open for its object and reason
+func (l *alertLifecycle) opensAlert(ev *kube.EnhancedEvent) bool {
+	if l.opens[ev.Reason] {
+		return true
+	}
+	return l.warnings && ev.Type == "Warning"
+}
+
+func (l *alertLifecycle) send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	object := alertObject(ev)
+
+	if problems, ok := l.resolvedBy[ev.Reason]; ok {
+		for _, problem := range problems {
+			key := object + "/" + problem
+			l.mu.Lock()
+			alert, open := l.open[key]
+			delete(l.open, key)
+			l.mu.Unlock()
+			if !open {
+				continue
+			}
+
+			if err := l.sink.resolveAlert(ctx, alert.id); err != nil {
+				l.mu.Lock()
+				l.open[key] = alert
+				l.mu.Unlock()
+				return err
+			}
+			log.Debug().Str("key", key).Str("reason", ev.Reason).Msg("Alert resolved")
+		}
+		return nil
+	}
+	if !l.opensAlert(ev) {
+		return nil
+	}
+
+	key := object + "/" + ev.Reason
+	l.mu.Lock()
+	if alert, open := l.open[key]; open {
+		alert.lastSeen = l.now()
+		l.mu.Unlock()
+		return nil
+	}
+	l.mu.Unlock()
+
+	id, err := l.sink.openAlert(ctx, ev, key)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.open[key] = &openAlert{id: id, lastSeen: l.now()}
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *alertLifecycle) run() {
+	defer close(l.doneCh)
+
+	interval := l.quiet / 10
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.resolveQuiet()
+		case <-l.stopCh:
+			return
+		}
+	}
+}
+
+// resolveQuiet resolves the alerts that were not seen during the quiet period
+func (l *alertLifecycle) resolveQuiet() {
+	current := l.now()
+	quiet := make(map[string]*openAlert)
+
+	l.mu.Lock()
+	for key, alert := range l.open {
+		if current.Sub(alert.lastSeen) >= l.quiet {
+			quiet[key] = alert
+			delete(l.open, key)
+		}
+	}
+	l.mu.Unlock()
+
+	for key, alert := range quiet {
+		if err := l.sink.resolveAlert(context.Background(), alert.id); err != nil {
+			log.Debug().Err(err).Str("key", key).Msg("Cannot resolve quiet alert")
+			continue
+		}
+		log.Debug().Str("key", key).Msg("Quiet alert resolved")
+	}
+}
+
+func (l *alertLifecycle) close() {
+	close(l.stopCh)
+	<-l.doneCh
+}
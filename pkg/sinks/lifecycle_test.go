@@ -0,0 +1,150 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type fakeAlertSink struct {
+	opened   []string
+	resolved []string
+}
+
+func (f *fakeAlertSink) openAlert(ctx context.Context, ev *kube.EnhancedEvent, key string) (string, error) {
+	id := fmt.Sprintf("alert-%d", len(f.opened))
+	f.opened = append(f.opened, key)
+	return id, nil
+}
+
+func (f *fakeAlertSink) resolveAlert(ctx context.Context, id string) error {
+	f.resolved = append(f.resolved, id)
+	return nil
+}
+
+func newLifecycleEvent(uid, reason string) *kube.EnhancedEvent {
+	ev := &kube.EnhancedEvent{}
+	ev.InvolvedObject.UID = types.UID(uid)
+	ev.Type = "Warning"
+	ev.Reason = reason
+	return ev
+}
+
+func TestAlertLifecycle_Recovery(t *testing.T) {
+	sink := &fakeAlertSink{}
+	l := newAlertLifecycle(sink, &LifecycleConfig{})
+	defer l.close()
+	ctx := context.Background()
+
+	require.NoError(t, l.send(ctx, newLifecycleEvent("pod-1", "BackOff")))
+	require.NoError(t, l.send(ctx, newLifecycleEvent("pod-1", "BackOff")))
+	require.NoError(t, l.send(ctx, newLifecycleEvent("pod-2", "BackOff")))
+	require.Equal(t, []string{"pod-1/BackOff", "pod-2/BackOff"}, sink.opened)
+
+	// Recovery events do not create alerts, even when nothing is open
+	require.NoError(t, l.send(ctx, newLifecycleEvent("pod-3", "Started")))
+	require.NoError(t, l.send(ctx, newLifecycleEvent("pod-1", "Started")))
+	require.Len(t, sink.opened, 2)
+	require.Equal(t, []string{"alert-0"}, sink.resolved)
+
+	// Once resolved, the next problem opens a new alert
+	require.NoError(t, l.send(ctx, newLifecycleEvent("pod-1", "BackOff")))
+	require.Equal(t, []string{"pod-1/BackOff", "pod-2/BackOff", "pod-1/BackOff"}, sink.opened)
+}
+
+func TestAlertLifecycle_QuietPeriod(t *testing.T) {
+	sink := &fakeAlertSink{}
+	l := newAlertLifecycle(sink, &LifecycleConfig{
+		Recoveries:         map[string][]string{"NodeNotReady": {"NodeReady"}},
+		QuietPeriodSeconds: 600,
+	})
+	defer l.close()
+
+	current := time.Now()
+	l.now = func() time.Time { return current }
+	ctx := context.Background()
+
+	require.NoError(t, l.send(ctx, newLifecycleEvent("node-1", "NodeNotReady")))
+	require.NoError(t, l.send(ctx, newLifecycleEvent("node-2", "NodeNotReady")))
+
+	current = current.Add(5 * time.Minute)
+	require.NoError(t, l.send(ctx, newLifecycleEvent("node-2", "NodeNotReady")))
+
+	current = current.Add(6 * time.Minute)
+	l.resolveQuiet()
+	require.Equal(t, []string{"alert-0"}, sink.resolved)
+
+	// Recoveries replace the default ones
+	require.NoError(t, l.send(ctx, newLifecycleEvent("pod-1", "BackOff")))
+	require.NoError(t, l.send(ctx, newLifecycleEvent("pod-1", "Started")))
+	require.Equal(t, []string{"alert-0"}, sink.resolved)
+}
+
+func TestAlertLifecycle_Reasons(t *testing.T) {
+	sink := &fakeAlertSink{}
+	l := newAlertLifecycle(sink, &LifecycleConfig{})
+	defer l.close()
+	ctx := context.Background()
+
+	// Normal events only open alerts for the reasons with recoveries
+	for _, reason := range []string{"Created", "Pulled", "Scheduled", "NodeNotReady"} {
+		ev := newLifecycleEvent("pod-1", reason)
+		ev.Type = "Normal"
+		require.NoError(t, l.send(ctx, ev))
+	}
+	require.Equal(t, []string{"pod-1/NodeNotReady"}, sink.opened)
+	require.Equal(t, defaultQuietPeriod, l.quiet)
+
+	sink = &fakeAlertSink{}
+	l = newAlertLifecycle(sink, &LifecycleConfig{Reasons: []string{"OOMKilling"}})
+	defer l.close()
+	require.NoError(t, l.send(ctx, newLifecycleEvent("pod-1", "BackOff")))
+	require.NoError(t, l.send(ctx, newLifecycleEvent("node-1", "OOMKilling")))
+	require.Equal(t, []string{"node-1/OOMKilling"}, sink.opened)
+}
+
+func TestLifecycleConfig_Validate(t *testing.T) {
+	r := ReceiverConfig{
+		Name:     "opsgenie",
+		Opsgenie: &OpsgenieConfig{Lifecycle: &LifecycleConfig{QuietPeriodSeconds: -1}},
+	}
+	require.Error(t, r.Validate())
+
+	r.Opsgenie.Lifecycle.QuietPeriodSeconds = 0
+	require.NoError(t, r.Validate())
+}
+
+type mockedUpdateOps struct {
+	mockedCreateOps
+	Update ssm.UpdateOpsItemInput
+}
+
+func (m *mockedUpdateOps) UpdateOpsItemWithContext(ctx aws.Context, in *ssm.UpdateOpsItemInput, o ...request.Option) (*ssm.UpdateOpsItemOutput, error) {
+	m.Update = *in
+	return &ssm.UpdateOpsItemOutput{}, nil
+}
+
+func TestOpsCenterSink_Lifecycle(t *testing.T) {
+	m := &mockedUpdateOps{mockedCreateOps: *newMockedCreateOps("oi-123")}
+	s := &OpsCenterSink{
+		cfg: &OpsCenterConfig{Title: "{{ .Reason }}", Description: "{{ .Message }}", Source: "k8s"},
+		svc: m,
+	}
+	s.lifecycle = newAlertLifecycle(s, &LifecycleConfig{})
+	defer s.Close()
+
+	require.NoError(t, s.Send(context.Background(), newLifecycleEvent("node-1", "NodeNotReady")))
+	require.Equal(t, "NodeNotReady", aws.StringValue(m.Input.Title))
+
+	require.NoError(t, s.Send(context.Background(), newLifecycleEvent("node-1", "NodeReady")))
+	require.Equal(t, "oi-123", aws.StringValue(m.Update.OpsItemId))
+	require.Equal(t, ssm.OpsItemStatusResolved, aws.StringValue(m.Update.Status))
+}
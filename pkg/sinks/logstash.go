@@ -1 +1,178 @@
 package sinks
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+)
+
+// LogstashConfig ships the events as JSON lines to Logstash over TCP, UDP or the HTTP input. The events are put in a
+// buffer of BufferSize events that is sent in the background, so a Logstash that is down only drops the events once
+// the buffer is full instead of blocking the exporter. The TCP connection is made again when it breaks.
+type LogstashConfig struct {
+	// Protocol is tcp, udp or http
+	Protocol       string                 `yaml:"protocol"`
+	Address        string                 `yaml:"address"`
+	URL            string                 `yaml:"url"`
+	Headers        map[string]string      `yaml:"headers"`
+	Layout         map[string]interface{} `yaml:"layout"`
+	LayoutRef      string                 `yaml:"layoutRef"`
+	DeDot          bool                   `yaml:"deDot"`
+	BufferSize     int                    `yaml:"bufferSize"`
+	TimeoutSeconds int                    `yaml:"timeoutSeconds"`
+	// TLS enables TLS for tcp, the https URLs of http use it as well
+	TLS *TLS `yaml:"tls"`
+}
+
+type LogstashSink struct {
+	cfg       *LogstashConfig
+	timeout   time.Duration
+	tlsConfig *tls.Config
+	client    *http.Client
+	conn      net.Conn
+	sender    *bufferedSender
+}
+
+func NewLogstashSink(cfg *LogstashConfig) (Sink, error) {
+	switch cfg.Protocol {
+	case "":
+		cfg.Protocol = "tcp"
+	case "tcp", "udp", "http":
+	default:
+		return nil, fmt.Errorf("logstash protocol must be tcp, udp or http, not %q", cfg.Protocol)
+	}
+	if cfg.Protocol == "http" && cfg.URL == "" {
+		return nil, fmt.Errorf("logstash url is required for http")
+	}
+	if cfg.Protocol != "http" && cfg.Address == "" {
+		return nil, fmt.Errorf("logstash address is required for %s", cfg.Protocol)
+	}
+	if cfg.BufferSize == 0 {
+		cfg.BufferSize = 1000
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 10
+	}
+
+	l := &LogstashSink{
+		cfg:     cfg,
+		timeout: time.Duration(cfg.TimeoutSeconds) * time.Second,
+	}
+
+	if cfg.TLS != nil {
+		tlsConfig, err := setupTLS(cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup TLS: %w", err)
+		}
+		l.tlsConfig = tlsConfig
+	}
+	if cfg.Protocol == "http" {
+		l.client = &http.Client{
+			Timeout: l.timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: l.tlsConfig,
+			},
+		}
+	}
+
+	l.sender = newBufferedSender("logstash", cfg.BufferSize, func(line interface{}) error {
+		return l.write(line.([]byte))
+	}, l.closeConn)
+	return l, nil
+}
+
+func (l *LogstashSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	if l.cfg.DeDot {
+		de := ev.DeDot()
+		ev = &de
+	}
+	line, err := serializeEventWithLayout(l.cfg.Layout, ev)
+	if err != nil {
+		return err
+	}
+
+	return l.sender.submit(line)
+}
+
+func (l *LogstashSink) write(line []byte) error {
+	if l.cfg.Protocol == "http" {
+		return l.post(line)
+	}
+
+	if l.conn == nil {
+		conn, err := l.dial()
+		if err != nil {
+			return err
+		}
+		l.conn = conn
+	}
+	if err := l.conn.SetWriteDeadline(time.Now().Add(l.timeout)); err != nil {
+		return err
+	}
+	if l.cfg.Protocol == "udp" {
+		_, err := l.conn.Write(line)
+		return err
+	}
+	_, err := l.conn.Write(append(line, '\n'))
+	return err
+}
+
+func (l *LogstashSink) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: l.timeout}
+	if l.cfg.Protocol == "tcp" && l.tlsConfig != nil {
+		return tls.DialWithDialer(dialer, "tcp", l.cfg.Address, l.tlsConfig)
+	}
+	return dialer.Dial(l.cfg.Protocol, l.cfg.Address)
+}
+
+func (l *LogstashSink) post(line []byte) error {
+	req, err := http.NewRequest(http.MethodPost, l.cfg.URL, bytes.NewReader(line))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range l.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		err := fmt.Errorf("logstash returned %d: %s", resp.StatusCode, string(body))
+		// Logstash rejects the event itself, e.g. when it is too large, and would do so again
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+			resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+			return &permanentError{err}
+		}
+		return err
+	}
+	return nil
+}
+
+func (l *LogstashSink) closeConn() {
+	if l.conn != nil {
+		_ = l.conn.Close()
+		l.conn = nil
+	}
+}
+
+// Close sends the buffered events unless Logstash cannot be reached
+func (l *LogstashSink) Close() {
+	l.sender.close()
+	if l.client != nil {
+		l.client.CloseIdleConnections()
+	}
+}
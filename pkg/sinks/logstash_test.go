@@ -0,0 +1,152 @@
+package sinks
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogstashSink_TCPReconnect(t *testing.T) {
+	// The address is free until the listener is started again, the first writes fail to connect
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	address := listener.Addr().String()
+	assert.NoError(t, listener.Close())
+
+	sink, err := NewLogstashSink(&LogstashConfig{
+		Address: address,
+		Layout:  map[string]interface{}{"reason": "{{ .Reason }}", "labels": "{{ toRaw .InvolvedObject.Labels }}"},
+		DeDot:   true,
+	})
+	assert.NoError(t, err)
+	sink.(*LogstashSink).sender.minBackoff = 10 * time.Millisecond
+
+	for _, reason := range []string{"First", "Second"} {
+		ev := newTestEvent()
+		ev.Reason = reason
+		ev.InvolvedObject.Labels = map[string]string{"app.kubernetes.io/name": "nginx"}
+		assert.NoError(t, sink.Send(context.Background(), ev))
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	listener, err = net.Listen("tcp", address)
+	assert.NoError(t, err)
+	defer listener.Close()
+	conn, err := listener.Accept()
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	reader := bufio.NewScanner(conn)
+	var lines []string
+	for len(lines) < 2 && reader.Scan() {
+		lines = append(lines, reader.Text())
+	}
+	assert.Equal(t, []string{
+		`{"labels":{"app_kubernetes_io/name":"nginx"},"reason":"First"}`,
+		`{"labels":{"app_kubernetes_io/name":"nginx"},"reason":"Second"}`,
+	}, lines)
+	sink.Close()
+}
+
+func TestLogstashSink_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	sink, err := NewLogstashSink(&LogstashConfig{Protocol: "udp", Address: conn.LocalAddr().String()})
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Send(context.Background(), newTestEvent()))
+
+	buf := make([]byte, 65536)
+	assert.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	sink.Close()
+
+	var event map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf[:n], &event))
+	assert.Equal(t, "BackOff", event["reason"])
+}
+
+func TestLogstashSink_HTTP(t *testing.T) {
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-Token"))
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		received <- string(body)
+	}))
+	defer server.Close()
+
+	sink, err := NewLogstashSink(&LogstashConfig{
+		Protocol: "http",
+		URL:      server.URL,
+		Headers:  map[string]string{"X-Token": "secret"},
+		Layout:   map[string]interface{}{"reason": "{{ .Reason }}"},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Send(context.Background(), newTestEvent()))
+	sink.Close()
+
+	assert.Equal(t, `{"reason":"BackOff"}`, <-received)
+}
+
+func TestLogstashSink_HTTPRejected(t *testing.T) {
+	var requests int
+	received := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		if requests == 1 {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		received <- string(body)
+	}))
+	defer server.Close()
+
+	sink, err := NewLogstashSink(&LogstashConfig{
+		Protocol: "http",
+		URL:      server.URL,
+		Layout:   map[string]interface{}{"reason": "{{ .Reason }}"},
+	})
+	assert.NoError(t, err)
+	sink.(*LogstashSink).sender.minBackoff = time.Hour
+
+	// The rejected event is dropped and the next one is sent
+	assert.NoError(t, sink.Send(context.Background(), newTestEvent()))
+	ev := newTestEvent()
+	ev.Reason = "Started"
+	assert.NoError(t, sink.Send(context.Background(), ev))
+	sink.Close()
+
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, `{"reason":"Started"}`, <-received)
+}
+
+func TestLogstashSink_BufferFull(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	address := listener.Addr().String()
+	assert.NoError(t, listener.Close())
+
+	sink, err := NewLogstashSink(&LogstashConfig{Address: address, BufferSize: 1})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	// One event is retried by the sender and one is in the buffer, the next ones are dropped
+	var dropped bool
+	for i := 0; i < 3 && !dropped; i++ {
+		dropped = sink.Send(context.Background(), newTestEvent()) != nil
+	}
+	assert.True(t, dropped)
+}
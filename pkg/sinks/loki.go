@@ -0,0 +1,365 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/resmoio/kubernetes-event-exporter/pkg/batch"
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	lokiPushPath          = "/loki/api/v1/push"
+	lokiOverflowValue     = "_overflow_"
+	defaultLokiLabelLimit = 100
+)
+
+var lokiLabelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// defaultLokiLabels keep the number of streams low, everything else is in the log line
+var defaultLokiLabels = map[string]string{
+	"job":       "kubernetes-event-exporter",
+	"namespace": "{{ .InvolvedObject.Namespace }}",
+	"kind":      "{{ .InvolvedObject.Kind }}",
+	"type":      "{{ .Type }}",
+}
+
+// LokiConfig pushes the events to Loki in batches. The labels are templates that make the streams, a label that
+// gets more than MaxLabelValues values gets _overflow_ for the new ones so that a bad template cannot create an
+// unbounded number of streams. The log line is the event or its layout.
+type LokiConfig struct {
+	URL             string                 `yaml:"url"`
+	TenantID        string                 `yaml:"tenantID"`
+	Username        string                 `yaml:"username"`
+	Password        string                 `yaml:"password"`
+	BearerToken     string                 `yaml:"bearerToken"`
+	Labels          map[string]string      `yaml:"labels"`
+	MaxLabelValues  int                    `yaml:"maxLabelValues"`
+	Format          string                 `yaml:"format"`
+	Layout          map[string]interface{} `yaml:"layout"`
+	LayoutRef       string                 `yaml:"layoutRef"`
+	TLS             TLS                    `yaml:"tls"`
+	BatchSize       int                    `yaml:"batchSize"`
+	MaxRetries      int                    `yaml:"maxRetries"`
+	IntervalSeconds int                    `yaml:"intervalSeconds"`
+	TimeoutSeconds  int                    `yaml:"timeoutSeconds"`
+}
+
+// lokiEntry is a line of a stream, labels is the sorted label set in the Loki format
+type lokiEntry struct {
+	labels    string
+	labelSet  map[string]string
+	timestamp time.Time
+	line      string
+}
+
+type lokiStream struct {
+	labelSet map[string]string
+	entries  []*lokiEntry
+}
+
+type LokiSink struct {
+	cfg         *LokiConfig
+	endpoint    string
+	client      *http.Client
+	batchWriter *batch.Writer
+
+	mu          sync.Mutex
+	labelValues map[string]map[string]bool
+}
+
+func NewLokiSink(cfg *LokiConfig) (Sink, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("loki url is required")
+	}
+	endpoint, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Path == "" || endpoint.Path == "/" {
+		endpoint.Path = lokiPushPath
+	}
+
+	switch cfg.Format {
+	case "":
+		cfg.Format = "json"
+	case "json", "protobuf":
+	default:
+		return nil, fmt.Errorf("loki format must be json or protobuf, not %q", cfg.Format)
+	}
+
+	if cfg.Labels == nil {
+		cfg.Labels = defaultLokiLabels
+	}
+	for name := range cfg.Labels {
+		if !lokiLabelName.MatchString(name) {
+			return nil, fmt.Errorf("invalid loki label name %q", name)
+		}
+	}
+	if cfg.MaxLabelValues == 0 {
+		cfg.MaxLabelValues = defaultLokiLabelLimit
+	}
+
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.IntervalSeconds == 0 {
+		cfg.IntervalSeconds = 5
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 30
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	l := &LokiSink{
+		cfg:      cfg,
+		endpoint: endpoint.String(),
+		client: &http.Client{
+			Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsClientConfig,
+			},
+		},
+		labelValues: make(map[string]map[string]bool),
+	}
+
+	l.batchWriter = batch.NewWriter(
+		batch.WriterConfig{
+			BatchSize:  cfg.BatchSize,
+			MaxRetries: cfg.MaxRetries,
+			Interval:   time.Duration(cfg.IntervalSeconds) * time.Second,
+			Timeout:    time.Duration(cfg.TimeoutSeconds) * time.Second,
+		},
+		l.handleBatch,
+	)
+	l.batchWriter.Start()
+	return l, nil
+}
+
+func (l *LokiSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	line, err := serializeEventWithLayout(l.cfg.Layout, ev)
+	if err != nil {
+		return err
+	}
+
+	labelSet := make(map[string]string, len(l.cfg.Labels))
+	for name, text := range l.cfg.Labels {
+		value, err := GetString(ev, text)
+		if err != nil {
+			return err
+		}
+		// Loki rejects empty label values, the label is left out instead
+		if value != "" {
+			labelSet[name] = l.guardLabelValue(name, value)
+		}
+	}
+
+	l.batchWriter.Submit(&lokiEntry{
+		labels:    formatLokiLabels(labelSet),
+		labelSet:  labelSet,
+		timestamp: lokiTimestamp(ev),
+		line:      string(line),
+	})
+	return nil
+}
+
+// guardLabelValue returns the overflow value once the label has too many values
+func (l *LokiSink) guardLabelValue(name, value string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	values, ok := l.labelValues[name]
+	if !ok {
+		values = make(map[string]bool)
+		l.labelValues[name] = values
+	}
+	if values[value] {
+		return value
+	}
+	if len(values) >= l.cfg.MaxLabelValues {
+		log.Warn().Str("label", name).Int("maxLabelValues", l.cfg.MaxLabelValues).
+			Msg("Loki label has too many values, new values are replaced")
+		return lokiOverflowValue
+	}
+	values[value] = true
+	return value
+}
+
+// lokiTimestamp is the last time the event happened, so repeated events do not go back in time in their stream
+func lokiTimestamp(ev *kube.EnhancedEvent) time.Time {
+	switch {
+	case !ev.LastTimestamp.IsZero():
+		return ev.LastTimestamp.Time
+	case !ev.EventTime.IsZero():
+		return ev.EventTime.Time
+	case !ev.FirstTimestamp.IsZero():
+		return ev.FirstTimestamp.Time
+	}
+	return time.Now()
+}
+
+func formatLokiLabels(labelSet map[string]string) string {
+	names := make([]string, 0, len(labelSet))
+	for name := range labelSet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + strconv.Quote(labelSet[name])
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+func (l *LokiSink) handleBatch(ctx context.Context, items []interface{}) []bool {
+	res := make([]bool, len(items))
+	err := l.push(ctx, items)
+	if err != nil {
+		log.Error().Err(err).Int("entries", len(items)).Msg("Cannot push events to Loki")
+	}
+	for i := range res {
+		res[i] = err == nil
+	}
+	return res
+}
+
+func (l *LokiSink) push(ctx context.Context, items []interface{}) error {
+	streams := make(map[string]*lokiStream)
+	keys := make([]string, 0)
+	for _, item := range items {
+		entry := item.(*lokiEntry)
+		stream, ok := streams[entry.labels]
+		if !ok {
+			stream = &lokiStream{labelSet: entry.labelSet}
+			streams[entry.labels] = stream
+			keys = append(keys, entry.labels)
+		}
+		stream.entries = append(stream.entries, entry)
+	}
+	// Loki expects the entries of a stream in order
+	for _, stream := range streams {
+		sort.SliceStable(stream.entries, func(i, j int) bool {
+			return stream.entries[i].timestamp.Before(stream.entries[j].timestamp)
+		})
+	}
+	sort.Strings(keys)
+
+	var body []byte
+	contentType := "application/json"
+	if l.cfg.Format == "protobuf" {
+		body = snappy.Encode(nil, encodeLokiPushRequest(keys, streams))
+		contentType = "application/x-protobuf"
+	} else {
+		var err error
+		if body, err = encodeLokiJSON(keys, streams); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if l.cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", l.cfg.TenantID)
+	}
+	if l.cfg.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+l.cfg.BearerToken)
+	} else if l.cfg.Username != "" {
+		req.SetBasicAuth(l.cfg.Username, l.cfg.Password)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("loki returned %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+func encodeLokiJSON(keys []string, streams map[string]*lokiStream) ([]byte, error) {
+	type jsonStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+
+	req := struct {
+		Streams []jsonStream `json:"streams"`
+	}{Streams: make([]jsonStream, 0, len(keys))}
+	for _, key := range keys {
+		stream := streams[key]
+		values := make([][2]string, len(stream.entries))
+		for i, entry := range stream.entries {
+			values[i] = [2]string{strconv.FormatInt(entry.timestamp.UnixNano(), 10), entry.line}
+		}
+		req.Streams = append(req.Streams, jsonStream{Stream: stream.labelSet, Values: values})
+	}
+	return json.Marshal(req)
+}
+
+// encodeLokiPushRequest encodes the logproto.PushRequest message of Loki:
+//
+//	PushRequest  { repeated Stream streams = 1; }
+//	Stream       { string labels = 1; repeated Entry entries = 2; }
+//	Entry        { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+func encodeLokiPushRequest(keys []string, streams map[string]*lokiStream) []byte {
+	var req []byte
+	for _, key := range keys {
+		var stream []byte
+		stream = protowire.AppendTag(stream, 1, protowire.BytesType)
+		stream = protowire.AppendString(stream, key)
+		for _, entry := range streams[key].entries {
+			var ts []byte
+			ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(entry.timestamp.Unix()))
+			ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(entry.timestamp.Nanosecond()))
+
+			var e []byte
+			e = protowire.AppendTag(e, 1, protowire.BytesType)
+			e = protowire.AppendBytes(e, ts)
+			e = protowire.AppendTag(e, 2, protowire.BytesType)
+			e = protowire.AppendString(e, entry.line)
+
+			stream = protowire.AppendTag(stream, 2, protowire.BytesType)
+			stream = protowire.AppendBytes(stream, e)
+		}
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, stream)
+	}
+	return req
+}
+
+func (l *LokiSink) Close() {
+	l.batchWriter.Stop()
+	l.client.CloseIdleConnections()
+}
@@ -0,0 +1,195 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type lokiRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func newLokiServer(t *testing.T, received *[]lokiRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, lokiPushPath, r.URL.Path)
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		*received = append(*received, lokiRequest{header: r.Header, body: body})
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func TestLokiSink_JSON(t *testing.T) {
+	var received []lokiRequest
+	server := newLokiServer(t, &received)
+	defer server.Close()
+
+	sink, err := NewLokiSink(&LokiConfig{
+		URL:      server.URL,
+		TenantID: "team-a",
+		Username: "user",
+		Password: "secret",
+		Layout:   map[string]interface{}{"message": "{{ .Message }}"},
+	})
+	assert.NoError(t, err)
+
+	now := time.Unix(1600000000, 0)
+	ev := newTestEvent()
+	ev.LastTimestamp = metav1.NewTime(now.Add(time.Second))
+	assert.NoError(t, sink.Send(context.Background(), ev))
+	ev = newTestEvent()
+	ev.LastTimestamp = metav1.NewTime(now)
+	assert.NoError(t, sink.Send(context.Background(), ev))
+	ev = newTestEvent()
+	ev.InvolvedObject.Namespace = "kube-system"
+	ev.LastTimestamp = metav1.NewTime(now)
+	assert.NoError(t, sink.Send(context.Background(), ev))
+	sink.Close()
+
+	assert.Len(t, received, 1)
+	assert.Equal(t, "team-a", received[0].header.Get("X-Scope-OrgID"))
+	assert.Equal(t, "application/json", received[0].header.Get("Content-Type"))
+	user, password, ok := (&http.Request{Header: received[0].header}).BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "secret", password)
+
+	var push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	assert.NoError(t, json.Unmarshal(received[0].body, &push))
+	assert.Len(t, push.Streams, 2)
+
+	stream := push.Streams[0]
+	assert.Equal(t, map[string]string{
+		"job":       "kubernetes-event-exporter",
+		"namespace": "default",
+		"kind":      "Pod",
+		"type":      "Warning",
+	}, stream.Stream)
+	assert.Equal(t, [][2]string{
+		{"1600000000000000000", `{"message":"Back-off restarting failed container"}`},
+		{"1600000001000000000", `{"message":"Back-off restarting failed container"}`},
+	}, stream.Values)
+	assert.Equal(t, "kube-system", push.Streams[1].Stream["namespace"])
+}
+
+func TestLokiSink_Protobuf(t *testing.T) {
+	var received []lokiRequest
+	server := newLokiServer(t, &received)
+	defer server.Close()
+
+	sink, err := NewLokiSink(&LokiConfig{
+		URL:         server.URL + lokiPushPath,
+		Format:      "protobuf",
+		BearerToken: "token",
+		Labels:      map[string]string{"reason": "{{ .Reason }}"},
+	})
+	assert.NoError(t, err)
+
+	ev := newTestEvent()
+	ev.LastTimestamp = metav1.NewTime(time.Unix(1600000000, 5))
+	assert.NoError(t, sink.Send(context.Background(), ev))
+	sink.Close()
+
+	assert.Len(t, received, 1)
+	assert.Equal(t, "application/x-protobuf", received[0].header.Get("Content-Type"))
+	assert.Equal(t, "Bearer token", received[0].header.Get("Authorization"))
+
+	raw, err := snappy.Decode(nil, received[0].body)
+	assert.NoError(t, err)
+
+	// PushRequest.streams
+	num, typ, n := protowire.ConsumeTag(raw)
+	assert.Equal(t, protowire.Number(1), num)
+	assert.Equal(t, protowire.BytesType, typ)
+	stream, m := protowire.ConsumeBytes(raw[n:])
+	assert.Equal(t, len(raw), n+m)
+
+	// Stream.labels
+	_, _, n = protowire.ConsumeTag(stream)
+	labels, m := protowire.ConsumeString(stream[n:])
+	assert.Equal(t, `{reason="BackOff"}`, labels)
+	stream = stream[n+m:]
+
+	// Stream.entries
+	num, _, n = protowire.ConsumeTag(stream)
+	assert.Equal(t, protowire.Number(2), num)
+	entry, _ := protowire.ConsumeBytes(stream[n:])
+
+	_, _, n = protowire.ConsumeTag(entry)
+	ts, m := protowire.ConsumeBytes(entry[n:])
+	entry = entry[n+m:]
+	_, _, n = protowire.ConsumeTag(ts)
+	seconds, m := protowire.ConsumeVarint(ts[n:])
+	assert.Equal(t, uint64(1600000000), seconds)
+	ts = ts[n+m:]
+	_, _, n = protowire.ConsumeTag(ts)
+	nanos, _ := protowire.ConsumeVarint(ts[n:])
+	assert.Equal(t, uint64(5), nanos)
+
+	_, _, n = protowire.ConsumeTag(entry)
+	line, _ := protowire.ConsumeString(entry[n:])
+	assert.Contains(t, line, `"reason":"BackOff"`)
+}
+
+func TestLokiSink_LabelValueLimit(t *testing.T) {
+	var received []lokiRequest
+	server := newLokiServer(t, &received)
+	defer server.Close()
+
+	sink, err := NewLokiSink(&LokiConfig{
+		URL:            server.URL,
+		Labels:         map[string]string{"name": "{{ .InvolvedObject.Namespace }}"},
+		MaxLabelValues: 2,
+	})
+	assert.NoError(t, err)
+
+	for _, namespace := range []string{"a", "b", "c", "a", "d"} {
+		ev := newTestEvent()
+		ev.InvolvedObject.Namespace = namespace
+		ev.LastTimestamp = metav1.NewTime(time.Unix(1600000000, 0))
+		assert.NoError(t, sink.Send(context.Background(), ev))
+	}
+	sink.Close()
+
+	var push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	assert.Len(t, received, 1)
+	assert.NoError(t, json.Unmarshal(received[0].body, &push))
+
+	counts := make(map[string]int)
+	for _, stream := range push.Streams {
+		counts[stream.Stream["name"]] = len(stream.Values)
+	}
+	assert.Equal(t, map[string]int{"a": 2, "b": 1, lokiOverflowValue: 2}, counts)
+}
+
+func TestLokiSink_Invalid(t *testing.T) {
+	_, err := NewLokiSink(&LokiConfig{})
+	assert.Error(t, err)
+
+	_, err = NewLokiSink(&LokiConfig{URL: "http://loki:3100", Format: "xml"})
+	assert.Error(t, err)
+
+	_, err = NewLokiSink(&LokiConfig{URL: "http://loki:3100", Labels: map[string]string{"bad-name": "x"}})
+	assert.Error(t, err)
+}
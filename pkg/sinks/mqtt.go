@@ -0,0 +1,307 @@
+package sinks
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	pahov3 "github.com/eclipse/paho.mqtt.golang"
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/rs/zerolog/log"
+)
+
+// MQTTConfig publishes the events to an MQTT broker with MQTT 3.1.1 or 5. The topic is a template. The events are put
+// in a buffer of BufferSize events that is published in the background, so while the broker is unreachable the
+// events wait in the buffer, and only the events that do not fit are dropped.
+type MQTTConfig struct {
+	// Broker is the URL of the broker, tcp://, ssl:// or tls://, the port defaults to 1883 or 8883 with TLS
+	Broker string `yaml:"broker"`
+	// Version is 3.1.1 or 5
+	Version          string                 `yaml:"version"`
+	ClientID         string                 `yaml:"clientID"`
+	Username         string                 `yaml:"username"`
+	Password         string                 `yaml:"password"`
+	Topic            string                 `yaml:"topic"`
+	QoS              int                    `yaml:"qos"`
+	Retained         bool                   `yaml:"retained"`
+	Layout           map[string]interface{} `yaml:"layout"`
+	LayoutRef        string                 `yaml:"layoutRef"`
+	BufferSize       int                    `yaml:"bufferSize"`
+	KeepAliveSeconds int                    `yaml:"keepAliveSeconds"`
+	TimeoutSeconds   int                    `yaml:"timeoutSeconds"`
+	TLS              *TLS                   `yaml:"tls"`
+}
+
+// mqttClient hides the differences of the MQTT 3.1.1 and 5 clients
+type mqttClient interface {
+	connect(ctx context.Context) error
+	connected() bool
+	publish(ctx context.Context, msg *mqttMessage) error
+	disconnect()
+}
+
+type mqttMessage struct {
+	topic   string
+	payload []byte
+}
+
+type MQTTSink struct {
+	cfg     *MQTTConfig
+	client  mqttClient
+	timeout time.Duration
+	sender  *bufferedSender
+}
+
+func NewMQTTSink(cfg *MQTTConfig) (Sink, error) {
+	if cfg.Broker == "" {
+		return nil, fmt.Errorf("mqtt broker is required")
+	}
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("mqtt topic is required")
+	}
+	if cfg.QoS < 0 || cfg.QoS > 2 {
+		return nil, fmt.Errorf("mqtt qos must be 0, 1 or 2, not %d", cfg.QoS)
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = "kubernetes-event-exporter"
+	}
+	if cfg.BufferSize == 0 {
+		cfg.BufferSize = 1000
+	}
+	if cfg.KeepAliveSeconds == 0 {
+		cfg.KeepAliveSeconds = 30
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 10
+	}
+
+	broker, err := url.Parse(cfg.Broker)
+	if err != nil {
+		return nil, err
+	}
+	var tlsConfig *tls.Config
+	if cfg.TLS != nil {
+		if tlsConfig, err = setupTLS(cfg.TLS); err != nil {
+			return nil, fmt.Errorf("failed to setup TLS: %w", err)
+		}
+	}
+
+	var client mqttClient
+	switch cfg.Version {
+	case "", "3.1.1":
+		cfg.Version = "3.1.1"
+		client = newMQTTv3Client(cfg, mqttDefaultPort(broker, mqttSecureScheme(broker.Scheme)), tlsConfig)
+	case "5":
+		secure := tlsConfig != nil || mqttSecureScheme(broker.Scheme)
+		client = &mqttV5Client{cfg: cfg, broker: mqttDefaultPort(broker, secure), secure: secure, tlsConfig: tlsConfig}
+	default:
+		return nil, fmt.Errorf("mqtt version must be 3.1.1 or 5, not %q", cfg.Version)
+	}
+
+	m := &MQTTSink{
+		cfg:     cfg,
+		client:  client,
+		timeout: time.Duration(cfg.TimeoutSeconds) * time.Second,
+	}
+	m.sender = newBufferedSender("mqtt", cfg.BufferSize, func(msg interface{}) error {
+		return m.publish(msg.(*mqttMessage))
+	}, client.disconnect)
+	return m, nil
+}
+
+// mqttSecureScheme returns whether the scheme of the broker URL is one of the TLS schemes of the MQTT clients
+func mqttSecureScheme(scheme string) bool {
+	switch scheme {
+	case "ssl", "tls", "mqtts", "mqtt+ssl", "tcps":
+		return true
+	}
+	return false
+}
+
+// mqttDefaultPort returns the broker URL with the default MQTT port, 1883 or 8883 with TLS, if it has none
+func mqttDefaultPort(broker *url.URL, secure bool) *url.URL {
+	if broker.Port() != "" || broker.Scheme == "ws" || broker.Scheme == "wss" || broker.Scheme == "unix" {
+		return broker
+	}
+	port := "1883"
+	if secure {
+		port = "8883"
+	}
+	withPort := *broker
+	withPort.Host = net.JoinHostPort(broker.Hostname(), port)
+	return &withPort
+}
+
+func (m *MQTTSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	payload, err := serializeEventWithLayout(m.cfg.Layout, ev)
+	if err != nil {
+		return err
+	}
+	topic, err := GetString(ev, m.cfg.Topic)
+	if err != nil {
+		return err
+	}
+
+	return m.sender.submit(&mqttMessage{topic: topic, payload: payload})
+}
+
+func (m *MQTTSink) publish(msg *mqttMessage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	if !m.client.connected() {
+		if err := m.client.connect(ctx); err != nil {
+			return err
+		}
+	}
+	return m.client.publish(ctx, msg)
+}
+
+// Close publishes the buffered events unless the broker cannot be reached
+func (m *MQTTSink) Close() {
+	m.sender.close()
+}
+
+type mqttV3Client struct {
+	cfg    *MQTTConfig
+	client pahov3.Client
+}
+
+func newMQTTv3Client(cfg *MQTTConfig, broker *url.URL, tlsConfig *tls.Config) *mqttV3Client {
+	opts := pahov3.NewClientOptions().
+		AddBroker(broker.String()).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetKeepAlive(time.Duration(cfg.KeepAliveSeconds) * time.Second).
+		SetConnectTimeout(time.Duration(cfg.TimeoutSeconds) * time.Second).
+		// The sink connects again itself, so that the buffer is the only place the events wait in
+		SetAutoReconnect(false).
+		SetCleanSession(true)
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+	return &mqttV3Client{cfg: cfg, client: pahov3.NewClient(opts)}
+}
+
+func (c *mqttV3Client) connect(ctx context.Context) error {
+	return waitMQTTToken(ctx, c.client.Connect())
+}
+
+func (c *mqttV3Client) connected() bool {
+	return c.client.IsConnectionOpen()
+}
+
+func (c *mqttV3Client) publish(ctx context.Context, msg *mqttMessage) error {
+	return waitMQTTToken(ctx, c.client.Publish(msg.topic, byte(c.cfg.QoS), c.cfg.Retained, msg.payload))
+}
+
+func (c *mqttV3Client) disconnect() {
+	if c.client.IsConnected() {
+		c.client.Disconnect(250)
+	}
+}
+
+func waitMQTTToken(ctx context.Context, token pahov3.Token) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type mqttV5Client struct {
+	cfg       *MQTTConfig
+	broker    *url.URL
+	secure    bool
+	tlsConfig *tls.Config
+	client    *paho.Client
+	// up is 1 from the CONNACK until the connection fails or the broker disconnects
+	up int32
+}
+
+func (c *mqttV5Client) connect(ctx context.Context) error {
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	switch {
+	case c.secure:
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: c.tlsConfig}).DialContext(ctx, "tcp", c.broker.Host)
+	default:
+		conn, err = dialer.DialContext(ctx, "tcp", c.broker.Host)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.client = paho.NewClient(paho.ClientConfig{
+		Conn: conn,
+		OnClientError: func(err error) {
+			atomic.StoreInt32(&c.up, 0)
+			log.Debug().Err(err).Msg("MQTT connection failed")
+		},
+		OnServerDisconnect: func(d *paho.Disconnect) {
+			atomic.StoreInt32(&c.up, 0)
+			log.Debug().Uint8("reasonCode", d.ReasonCode).Msg("MQTT broker disconnected")
+		},
+	})
+
+	connect := &paho.Connect{
+		ClientID:   c.cfg.ClientID,
+		KeepAlive:  uint16(c.cfg.KeepAliveSeconds),
+		CleanStart: true,
+	}
+	if c.cfg.Username != "" {
+		connect.Username = c.cfg.Username
+		connect.UsernameFlag = true
+	}
+	if c.cfg.Password != "" {
+		connect.Password = []byte(c.cfg.Password)
+		connect.PasswordFlag = true
+	}
+
+	connack, err := c.client.Connect(ctx, connect)
+	if err != nil {
+		return err
+	}
+	if connack.ReasonCode >= 0x80 {
+		return fmt.Errorf("mqtt broker refused the connection with reason code %d", connack.ReasonCode)
+	}
+	atomic.StoreInt32(&c.up, 1)
+	return nil
+}
+
+func (c *mqttV5Client) connected() bool {
+	return c.client != nil && atomic.LoadInt32(&c.up) == 1
+}
+
+func (c *mqttV5Client) publish(ctx context.Context, msg *mqttMessage) error {
+	resp, err := c.client.Publish(ctx, &paho.Publish{
+		Topic:   msg.topic,
+		QoS:     byte(c.cfg.QoS),
+		Retain:  c.cfg.Retained,
+		Payload: msg.payload,
+	})
+	// The broker rejects the message itself, e.g. a topic the user is not authorized to publish to, and would do so
+	// again
+	if resp != nil && resp.ReasonCode >= 0x80 {
+		return &permanentError{fmt.Errorf("mqtt broker rejected the message with reason code %d", resp.ReasonCode)}
+	}
+	return err
+}
+
+func (c *mqttV5Client) disconnect() {
+	if c.client != nil {
+		if c.connected() {
+			_ = c.client.Disconnect(&paho.Disconnect{ReasonCode: 0})
+		}
+		atomic.StoreInt32(&c.up, 0)
+		c.client = nil
+	}
+}
@@ -0,0 +1,196 @@
+package sinks
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	mqtt "github.com/mochi-co/mqtt/v2"
+	"github.com/mochi-co/mqtt/v2/hooks/auth"
+	"github.com/mochi-co/mqtt/v2/listeners"
+	"github.com/mochi-co/mqtt/v2/packets"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+type mqttPublished struct {
+	version  byte
+	username string
+	topic    string
+	qos      byte
+	retain   bool
+	payload  string
+}
+
+// mqttRecorder is a hook of the broker that records the publishes
+type mqttRecorder struct {
+	mqtt.HookBase
+	published chan mqttPublished
+}
+
+func (h *mqttRecorder) ID() string {
+	return "recorder"
+}
+
+func (h *mqttRecorder) Provides(b byte) bool {
+	return b == mqtt.OnPublish
+}
+
+func (h *mqttRecorder) OnPublish(cl *mqtt.Client, pk packets.Packet) (packets.Packet, error) {
+	h.published <- mqttPublished{
+		version:  cl.Properties.ProtocolVersion,
+		username: string(cl.Properties.Username),
+		topic:    pk.TopicName,
+		qos:      pk.FixedHeader.Qos,
+		retain:   pk.FixedHeader.Retain,
+		payload:  string(pk.Payload),
+	}
+	return pk, nil
+}
+
+func newMQTTBroker(t *testing.T, address string) *mqttRecorder {
+	server := mqtt.New(nil)
+	logger := zerolog.Nop()
+	server.Log = &logger
+	assert.NoError(t, server.AddHook(new(auth.AllowHook), nil))
+	recorder := &mqttRecorder{published: make(chan mqttPublished, 10)}
+	assert.NoError(t, server.AddHook(recorder, nil))
+	assert.NoError(t, server.AddListener(listeners.NewTCP("tcp", address, nil)))
+	assert.NoError(t, server.Serve())
+	t.Cleanup(func() { server.Close() })
+	return recorder
+}
+
+func freeMQTTAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func receiveMQTT(t *testing.T, recorder *mqttRecorder) mqttPublished {
+	select {
+	case published := <-recorder.published:
+		return published
+	case <-time.After(10 * time.Second):
+		t.Fatal("no message was published")
+	}
+	return mqttPublished{}
+}
+
+func TestMQTTSink_Versions(t *testing.T) {
+	for _, tc := range []struct {
+		version         string
+		protocolVersion byte
+		qos             int
+	}{
+		{"3.1.1", 4, 1},
+		{"5", 5, 2},
+	} {
+		t.Run(tc.version, func(t *testing.T) {
+			address := freeMQTTAddress(t)
+			recorder := newMQTTBroker(t, address)
+
+			sink, err := NewMQTTSink(&MQTTConfig{
+				Broker:   "tcp://" + address,
+				Version:  tc.version,
+				Username: "exporter",
+				Password: "secret",
+				Topic:    "k8s/{{ .InvolvedObject.Namespace }}/{{ .Reason }}",
+				QoS:      tc.qos,
+				Retained: true,
+				Layout:   map[string]interface{}{"reason": "{{ .Reason }}"},
+			})
+			assert.NoError(t, err)
+			defer sink.Close()
+
+			assert.NoError(t, sink.Send(context.Background(), newTestEvent()))
+			assert.Equal(t, mqttPublished{
+				version:  tc.protocolVersion,
+				username: "exporter",
+				topic:    "k8s/default/BackOff",
+				qos:      byte(tc.qos),
+				retain:   true,
+				payload:  `{"reason":"BackOff"}`,
+			}, receiveMQTT(t, recorder))
+		})
+	}
+}
+
+func TestMQTTSink_OfflineBuffer(t *testing.T) {
+	address := freeMQTTAddress(t)
+
+	sink, err := NewMQTTSink(&MQTTConfig{
+		Broker:  "tcp://" + address,
+		Version: "5",
+		Topic:   "k8s/{{ .InvolvedObject.Namespace }}",
+		QoS:     1,
+	})
+	assert.NoError(t, err)
+	sink.(*MQTTSink).sender.minBackoff = 10 * time.Millisecond
+	defer sink.Close()
+
+	// The events wait in the buffer until the broker is started
+	for _, namespace := range []string{"a", "b"} {
+		ev := newTestEvent()
+		ev.InvolvedObject.Namespace = namespace
+		assert.NoError(t, sink.Send(context.Background(), ev))
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	recorder := newMQTTBroker(t, address)
+	assert.Equal(t, "k8s/a", receiveMQTT(t, recorder).topic)
+	assert.Equal(t, "k8s/b", receiveMQTT(t, recorder).topic)
+}
+
+func TestMQTTSink_DefaultPort(t *testing.T) {
+	for _, tc := range []struct {
+		broker string
+		secure bool
+		host   string
+	}{
+		{"tcp://mqtt", false, "mqtt:1883"},
+		{"ssl://mqtt", true, "mqtt:8883"},
+		{"tcp://mqtt:1884", false, "mqtt:1884"},
+		{"tcp://[::1]", false, "[::1]:1883"},
+		{"ws://mqtt/mqtt", false, "mqtt"},
+	} {
+		broker, err := url.Parse(tc.broker)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.host, mqttDefaultPort(broker, tc.secure).Host, tc.broker)
+	}
+}
+
+func TestMQTTSink_V5WithoutPort(t *testing.T) {
+	// The broker needs the default port, which may be taken
+	listener, err := net.Listen("tcp", "127.0.0.1:1883")
+	if err != nil {
+		t.Skipf("port 1883 is not available: %v", err)
+	}
+	assert.NoError(t, listener.Close())
+	recorder := newMQTTBroker(t, "127.0.0.1:1883")
+
+	sink, err := NewMQTTSink(&MQTTConfig{
+		Broker:  "tcp://127.0.0.1",
+		Version: "5",
+		Topic:   "k8s/{{ .Reason }}",
+	})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	assert.NoError(t, sink.Send(context.Background(), newTestEvent()))
+	assert.Equal(t, "k8s/BackOff", receiveMQTT(t, recorder).topic)
+}
+
+func TestMQTTSink_Invalid(t *testing.T) {
+	_, err := NewMQTTSink(&MQTTConfig{Topic: "events"})
+	assert.Error(t, err)
+
+	_, err = NewMQTTSink(&MQTTConfig{Broker: "tcp://mqtt:1883", Topic: "events", QoS: 3})
+	assert.Error(t, err)
+
+	_, err = NewMQTTSink(&MQTTConfig{Broker: "tcp://mqtt:1883", Topic: "events", Version: "4"})
+	assert.Error(t, err)
+}
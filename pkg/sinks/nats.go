@@ -0,0 +1,145 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+)
+
+// NATSConfig publishes the events to a subject of NATS, the subject is a template. With JetStream, every publish
+// waits for the acknowledgment of the stream and has the UID and the count of the event as message ID, so the
+// stream drops the duplicates. Credentials is a creds file, NKeySeedFile a file with the seed of a user NKey. A server
+// that cannot be reached when the exporter starts is connected to in the background like after a disconnect.
+type NATSConfig struct {
+	URL            string                 `yaml:"url"`
+	Subject        string                 `yaml:"subject"`
+	JetStream      bool                   `yaml:"jetStream"`
+	Credentials    string                 `yaml:"credentials"`
+	NKeySeedFile   string                 `yaml:"nkeySeedFile"`
+	Username       string                 `yaml:"username"`
+	Password       string                 `yaml:"password"`
+	Token          string                 `yaml:"token"`
+	Layout         map[string]interface{} `yaml:"layout"`
+	LayoutRef      string                 `yaml:"layoutRef"`
+	TimeoutSeconds int                    `yaml:"timeoutSeconds"`
+	TLS            *TLS                   `yaml:"tls"`
+}
+
+type NATSSink struct {
+	cfg     *NATSConfig
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	timeout time.Duration
+}
+
+func NewNATSSink(cfg *NATSConfig) (Sink, error) {
+	if cfg.Subject == "" {
+		return nil, fmt.Errorf("nats subject is required")
+	}
+	if cfg.URL == "" {
+		cfg.URL = nats.DefaultURL
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 5
+	}
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+
+	opts := []nats.Option{
+		nats.Name("kubernetes-event-exporter"),
+		nats.Timeout(timeout),
+		nats.MaxReconnects(-1),
+		nats.RetryOnFailedConnect(true),
+	}
+	if cfg.Credentials != "" {
+		opts = append(opts, nats.UserCredentials(cfg.Credentials))
+	}
+	if cfg.NKeySeedFile != "" {
+		opt, err := nats.NkeyOptionFromSeed(cfg.NKeySeedFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, opt)
+	}
+	if cfg.Username != "" {
+		opts = append(opts, nats.UserInfo(cfg.Username, cfg.Password))
+	}
+	if cfg.Token != "" {
+		opts = append(opts, nats.Token(cfg.Token))
+	}
+	if cfg.TLS != nil {
+		tlsConfig, err := setupTLS(cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup TLS: %w", err)
+		}
+		opts = append(opts, nats.Secure(tlsConfig))
+	}
+
+	conn, err := nats.Connect(cfg.URL, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &NATSSink{cfg: cfg, conn: conn, timeout: timeout}
+	if cfg.JetStream {
+		n.js, err = conn.JetStream(nats.MaxWait(timeout))
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+func (n *NATSSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	data, err := serializeEventWithLayout(n.cfg.Layout, ev)
+	if err != nil {
+		return err
+	}
+
+	subject, err := GetString(ev, n.cfg.Subject)
+	if err != nil {
+		return err
+	}
+	if err := validateNATSSubject(subject); err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	if n.js == nil {
+		return n.conn.PublishMsg(msg)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+	_, err = n.js.PublishMsg(msg, nats.MsgId(natsMsgID(ev)), nats.Context(ctx))
+	return err
+}
+
+// natsMsgID is the same for the redeliveries of an event and changes when the event happens again
+func natsMsgID(ev *kube.EnhancedEvent) string {
+	return string(ev.UID) + "-" + strconv.Itoa(int(ev.Count))
+}
+
+// validateNATSSubject rejects the subjects NATS does not accept, e.g. a template with an empty value in the middle
+func validateNATSSubject(subject string) error {
+	if strings.ContainsAny(subject, " \t\r\n") {
+		return fmt.Errorf("nats subject %q contains whitespace", subject)
+	}
+	for _, token := range strings.Split(subject, ".") {
+		if token == "" {
+			return fmt.Errorf("nats subject %q has an empty token", subject)
+		}
+	}
+	return nil
+}
+
+func (n *NATSSink) Close() {
+	_ = n.conn.FlushTimeout(n.timeout)
+	n.conn.Close()
+}
@@ -0,0 +1,123 @@
+package sinks
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+func newNATSServer(t *testing.T, opts *server.Options) *server.Server {
+	opts.Host = "127.0.0.1"
+	if opts.Port == 0 {
+		opts.Port = -1
+	}
+	opts.NoLog = true
+	opts.NoSigs = true
+	s, err := server.NewServer(opts)
+	assert.NoError(t, err)
+	go s.Start()
+	assert.True(t, s.ReadyForConnections(5*time.Second))
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func TestNATSSink_Publish(t *testing.T) {
+	s := newNATSServer(t, &server.Options{Authorization: "token"})
+
+	conn, err := nats.Connect(s.ClientURL(), nats.Token("token"))
+	assert.NoError(t, err)
+	defer conn.Close()
+	sub, err := conn.SubscribeSync("k8s.events.>")
+	assert.NoError(t, err)
+	assert.NoError(t, conn.Flush())
+
+	sink, err := NewNATSSink(&NATSConfig{
+		URL:     s.ClientURL(),
+		Token:   "token",
+		Subject: "k8s.events.{{ .Namespace }}.{{ .Reason }}",
+		Layout:  map[string]interface{}{"reason": "{{ .Reason }}"},
+	})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	ev := newTestEvent()
+	ev.Namespace = "default"
+	assert.NoError(t, sink.Send(context.Background(), ev))
+	assert.Error(t, sink.Send(context.Background(), newTestEvent()))
+
+	msg, err := sub.NextMsg(5 * time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "k8s.events.default.BackOff", msg.Subject)
+	assert.Equal(t, `{"reason":"BackOff"}`, string(msg.Data))
+}
+
+func TestNATSSink_JetStream(t *testing.T) {
+	s := newNATSServer(t, &server.Options{JetStream: true, StoreDir: t.TempDir()})
+
+	conn, err := nats.Connect(s.ClientURL())
+	assert.NoError(t, err)
+	defer conn.Close()
+	js, err := conn.JetStream()
+	assert.NoError(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{Name: "EVENTS", Subjects: []string{"events.>"}})
+	assert.NoError(t, err)
+
+	sink, err := NewNATSSink(&NATSConfig{
+		URL:       s.ClientURL(),
+		Subject:   "events.{{ .Namespace }}",
+		JetStream: true,
+	})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	// The same event is only stored once, the next occurrence has another count
+	ev := newTestEvent()
+	ev.UID = "uid-1"
+	ev.Namespace = "default"
+	ev.Count = 1
+	assert.NoError(t, sink.Send(context.Background(), ev))
+	assert.NoError(t, sink.Send(context.Background(), ev))
+	ev.Count = 2
+	assert.NoError(t, sink.Send(context.Background(), ev))
+
+	info, err := js.StreamInfo("EVENTS")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), info.State.Msgs)
+
+	// Without a stream for the subject there is no acknowledgment
+	assert.Error(t, sink.Send(context.Background(), newTestEvent()))
+}
+
+func TestNATSSink_ConnectLater(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	assert.NoError(t, listener.Close())
+
+	// The server is not running yet, the sink connects once it is
+	sink, err := NewNATSSink(&NATSConfig{
+		URL:     "nats://127.0.0.1:" + strconv.Itoa(port),
+		Subject: "events",
+	})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	s := newNATSServer(t, &server.Options{Port: port})
+	conn, err := nats.Connect(s.ClientURL())
+	assert.NoError(t, err)
+	defer conn.Close()
+	sub, err := conn.SubscribeSync("events")
+	assert.NoError(t, err)
+	assert.NoError(t, conn.Flush())
+
+	assert.Eventually(t, sink.(*NATSSink).conn.IsConnected, 10*time.Second, 10*time.Millisecond)
+	assert.NoError(t, sink.Send(context.Background(), newTestEvent()))
+	_, err = sub.NextMsg(5 * time.Second)
+	assert.NoError(t, err)
+}
@@ -30,6 +30,7 @@ type OpenSearchConfig struct {
 	Type        string                 `yaml:"type"`
 	TLS         TLS                    `yaml:"tls"`
 	Layout      map[string]interface{} `yaml:"layout"`
+	LayoutRef   string                 `yaml:"layoutRef"`
 }
 
 func NewOpenSearch(cfg *OpenSearchConfig) (*OpenSearch, error) {
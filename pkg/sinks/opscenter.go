@@ -22,15 +22,26 @@ type OpsCenterConfig struct {
 	Region          string            `yaml:"region"`
 	RelatedOpsItems []string          `yaml:"relatedOpsItems"`
 	Severity        string            `yaml:"severity"`
+	UseSeverity     bool              `yaml:"useSeverity"`
 	Source          string            `yaml:"source"`
 	Tags            map[string]string `yaml:"tags"`
 	Title           string            `yaml:"title"`
+	Lifecycle       *LifecycleConfig  `yaml:"lifecycle"`
+}
+
+// opsCenterSeverities maps the severities to the OpsItem severities, 1 is the highest
+var opsCenterSeverities = map[string]string{
+	kube.SeverityCritical: "1",
+	kube.SeverityError:    "2",
+	kube.SeverityWarning:  "3",
+	kube.SeverityInfo:     "4",
 }
 
 // OpsCenterSink is an AWS OpsCenter notifcation path.
 type OpsCenterSink struct {
-	cfg *OpsCenterConfig
-	svc ssmiface.SSMAPI
+	cfg       *OpsCenterConfig
+	svc       ssmiface.SSMAPI
+	lifecycle *alertLifecycle
 }
 
 // NewOpsCenterSink returns a new OpsCenterSink.
@@ -43,28 +54,41 @@ func NewOpsCenterSink(cfg *OpsCenterConfig) (Sink, error) {
 	}
 
 	svc := ssm.New(sess)
-	return &OpsCenterSink{
+	sink := &OpsCenterSink{
 		cfg: cfg,
 		svc: svc,
-	}, nil
+	}
+	if cfg.Lifecycle != nil {
+		sink.lifecycle = newAlertLifecycle(sink, cfg.Lifecycle)
+	}
+	return sink, nil
 }
 
 // Send ...
 func (s *OpsCenterSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	if s.lifecycle != nil {
+		return s.lifecycle.send(ctx, ev)
+	}
+	_, err := s.openAlert(ctx, ev, "")
+	return err
+}
+
+// openAlert creates the OpsItem and returns its ID
+func (s *OpsCenterSink) openAlert(ctx context.Context, ev *kube.EnhancedEvent, key string) (string, error) {
 	oi := ssm.CreateOpsItemInput{}
 	t, err := GetString(ev, s.cfg.Title)
 	if err != nil {
-		return err
+		return "", err
 	}
 	oi.Title = aws.String(t)
 	d, err := GetString(ev, s.cfg.Description)
 	if err != nil {
-		return err
+		return "", err
 	}
 	oi.Description = aws.String(d)
 	su, err := GetString(ev, s.cfg.Source)
 	if err != nil {
-		return err
+		return "", err
 	}
 	oi.Source = aws.String(su)
 
@@ -72,16 +96,19 @@ func (s *OpsCenterSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error
 	if len(s.cfg.Category) != 0 {
 		c, err := GetString(ev, s.cfg.Category)
 		if err != nil {
-			return err
+			return "", err
 		}
 		oi.Category = aws.String(c)
 	}
 
-	// Severity is optional although highly recommended
+	// Severity is optional although highly recommended, with useSeverity the severity of the event is the default
+	if s.cfg.UseSeverity {
+		oi.Severity = aws.String(opsCenterSeverities[ev.GetSeverity()])
+	}
 	if len(s.cfg.Severity) != 0 {
 		se, err := GetString(ev, s.cfg.Severity)
 		if err != nil {
-			return err
+			return "", err
 		}
 		oi.Severity = aws.String(se)
 	}
@@ -90,11 +117,11 @@ func (s *OpsCenterSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error
 	if len(s.cfg.Priority) != 0 {
 		p, err := GetString(ev, s.cfg.Priority)
 		if err != nil {
-			return err
+			return "", err
 		}
 		n, err := strconv.ParseInt(p, 10, 64)
 		if err != nil {
-			return fmt.Errorf("Priority is a non int")
+			return "", fmt.Errorf("Priority is a non int")
 		}
 		oi.Priority = aws.Int64(n)
 	}
@@ -103,7 +130,7 @@ func (s *OpsCenterSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error
 		for k, v := range s.cfg.OperationalData {
 			dv, err := GetString(ev, v)
 			if err != nil {
-				return err
+				return "", err
 			}
 			oids[k] = &ssm.OpsItemDataValue{Type: aws.String("SearchableString"), Value: aws.String(dv)}
 		}
@@ -114,7 +141,7 @@ func (s *OpsCenterSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error
 		for k, v := range s.cfg.Tags {
 			tv, err := GetString(ev, v)
 			if err != nil {
-				return err
+				return "", err
 			}
 			tvs = append(tvs, &ssm.Tag{Key: aws.String(k), Value: aws.String(tv)})
 		}
@@ -125,7 +152,7 @@ func (s *OpsCenterSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error
 		for _, v := range s.cfg.OperationalData {
 			ri, err := GetString(ev, v)
 			if err != nil {
-				return err
+				return "", err
 			}
 			ris = append(ris, &ssm.RelatedOpsItem{OpsItemId: aws.String(ri)})
 		}
@@ -136,19 +163,32 @@ func (s *OpsCenterSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error
 		for _, v := range s.cfg.Notifications {
 			n, err := GetString(ev, v)
 			if err != nil {
-				return err
+				return "", err
 			}
 			ns = append(ns, &ssm.OpsItemNotification{Arn: aws.String(n)})
 		}
 		oi.Notifications = ns
 	}
 
-	_, createErr := s.svc.CreateOpsItemWithContext(ctx, &oi)
+	out, createErr := s.svc.CreateOpsItemWithContext(ctx, &oi)
+	if createErr != nil {
+		return "", createErr
+	}
+	return aws.StringValue(out.OpsItemId), nil
+}
 
-	return createErr
+// resolveAlert sets the status of the OpsItem to resolved
+func (s *OpsCenterSink) resolveAlert(ctx context.Context, id string) error {
+	_, err := s.svc.UpdateOpsItemWithContext(ctx, &ssm.UpdateOpsItemInput{
+		OpsItemId: aws.String(id),
+		Status:    aws.String(ssm.OpsItemStatusResolved),
+	})
+	return err
 }
 
 // Close ...
 func (s *OpsCenterSink) Close() {
-	// No-op
+	if s.lifecycle != nil {
+		s.lifecycle.close()
+	}
 }
@@ -122,6 +122,23 @@ func TestOpsCenterSink_Send(t *testing.T) {
 				Title:           aws.String("Successfully pulled image \"nginx:latest\""),
 			},
 		},
+		{"Severity from the event", fields{
+			&OpsCenterConfig{
+				Title:       "{{ .Message }}",
+				Description: "Event {{ .Reason }}",
+				Region:      "us-east1",
+				Source:      "production",
+				UseSeverity: true,
+			},
+			m,
+		}, args{context.Background(), ev}, false,
+			ssm.CreateOpsItemInput{
+				Description: aws.String("Event my reason"),
+				Severity:    aws.String("3"),
+				Source:      aws.String("production"),
+				Title:       aws.String("Successfully pulled image \"nginx:latest\""),
+			},
+		},
 		{"Invalid Priority: Want err", fields{
 			&OpsCenterConfig{
 				Title:           "{{ .Message }}",
@@ -7,20 +7,33 @@ import (
 	"github.com/opsgenie/opsgenie-go-sdk-v2/client"
 )
 
+// OpsgenieConfig sends the events as alerts. Without a priority, the alerts are P3, or with UseSeverity the
+// priority follows the severity of the event.
 type OpsgenieConfig struct {
 	ApiKey      string            `yaml:"apiKey"`
 	URL         client.ApiUrl     `yaml:"URL"`
 	Priority    string            `yaml:"priority"`
+	UseSeverity bool              `yaml:"useSeverity"`
 	Message     string            `yaml:"message"`
 	Alias       string            `yaml:"alias"`
 	Description string            `yaml:"description"`
 	Tags        []string          `yaml:"tags"`
 	Details     map[string]string `yaml:"details"`
+	Lifecycle   *LifecycleConfig  `yaml:"lifecycle"`
+}
+
+// opsgeniePriorities maps the severities to the Opsgenie priorities
+var opsgeniePriorities = map[string]alert.Priority{
+	kube.SeverityCritical: alert.P1,
+	kube.SeverityError:    alert.P2,
+	kube.SeverityWarning:  alert.P3,
+	kube.SeverityInfo:     alert.P5,
 }
 
 type OpsgenieSink struct {
 	cfg         *OpsgenieConfig
 	alertClient *alert.Client
+	lifecycle   *alertLifecycle
 }
 
 func NewOpsgenieSink(config *OpsgenieConfig) (Sink, error) {
@@ -28,7 +41,7 @@ func NewOpsgenieSink(config *OpsgenieConfig) (Sink, error) {
 		config.URL = client.API_URL
 	}
 
-	if config.Priority == "" {
+	if config.Priority == "" && !config.UseSeverity {
 		config.Priority = "P3"
 	}
 
@@ -41,35 +54,58 @@ func NewOpsgenieSink(config *OpsgenieConfig) (Sink, error) {
 		return nil, err
 	}
 
-	return &OpsgenieSink{
+	sink := &OpsgenieSink{
 		cfg:         config,
 		alertClient: alertClient,
-	}, nil
+	}
+	if config.Lifecycle != nil {
+		sink.lifecycle = newAlertLifecycle(sink, config.Lifecycle)
+	}
+	return sink, nil
 }
 
 func (o *OpsgenieSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	if o.lifecycle != nil {
+		return o.lifecycle.send(ctx, ev)
+	}
+	_, err := o.openAlert(ctx, ev, "")
+	return err
+}
+
+// openAlert creates the alert, its alias is the key of the lifecycle if no alias is configured
+func (o *OpsgenieSink) openAlert(ctx context.Context, ev *kube.EnhancedEvent, key string) (string, error) {
 	request := alert.CreateAlertRequest{
-		Priority: alert.Priority(o.cfg.Priority),
+		Priority: opsgeniePriorities[ev.GetSeverity()],
+	}
+
+	// With useSeverity and without a configured priority, the severity of the event is used
+	if o.cfg.Priority != "" {
+		priority, err := GetString(ev, o.cfg.Priority)
+		if err != nil {
+			return "", err
+		}
+		request.Priority = alert.Priority(priority)
 	}
 
 	msg, err := GetString(ev, o.cfg.Message)
 	if err != nil {
-		return err
+		return "", err
 	}
 	request.Message = msg
 
 	// Alias is optional although highly recommended to work
+	request.Alias = key
 	if o.cfg.Alias != "" {
 		alias, err := GetString(ev, o.cfg.Alias)
 		if err != nil {
-			return err
+			return "", err
 		}
 		request.Alias = alias
 	}
 
 	description, err := GetString(ev, o.cfg.Description)
 	if err != nil {
-		return err
+		return "", err
 	}
 	request.Description = description
 
@@ -78,7 +114,7 @@ func (o *OpsgenieSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
 		for _, v := range o.cfg.Tags {
 			tag, err := GetString(ev, v)
 			if err != nil {
-				return err
+				return "", err
 			}
 			tags = append(tags, tag)
 		}
@@ -90,7 +126,7 @@ func (o *OpsgenieSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
 		for k, v := range o.cfg.Details {
 			detail, err := GetString(ev, v)
 			if err != nil {
-				return err
+				return "", err
 			}
 			details[k] = detail
 		}
@@ -98,9 +134,22 @@ func (o *OpsgenieSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
 	}
 
 	_, err = o.alertClient.Create(ctx, &request)
+	return request.Alias, err
+}
+
+// resolveAlert closes the alert with the alias
+func (o *OpsgenieSink) resolveAlert(ctx context.Context, alias string) error {
+	_, err := o.alertClient.Close(ctx, &alert.CloseAlertRequest{
+		IdentifierType:  alert.ALIAS,
+		IdentifierValue: alias,
+		Source:          "kubernetes-event-exporter",
+		Note:            "Resolved by kubernetes-event-exporter",
+	})
 	return err
 }
 
 func (o *OpsgenieSink) Close() {
-	// No-op
+	if o.lifecycle != nil {
+		o.lifecycle.close()
+	}
 }
@@ -0,0 +1,20 @@
+package sinks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOpsgenieSink_DefaultPriority(t *testing.T) {
+	cfg := &OpsgenieConfig{ApiKey: "key"}
+	_, err := NewOpsgenieSink(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "P3", cfg.Priority)
+
+	// The priority follows the severity only when it is asked for
+	cfg = &OpsgenieConfig{ApiKey: "key", UseSeverity: true}
+	_, err = NewOpsgenieSink(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "", cfg.Priority)
+}
@@ -0,0 +1,211 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+)
+
+const (
+	pagerDutyEventsURL  = "https://events.pagerduty.com/v2/enqueue"
+	pagerDutyMaxSummary = 1024
+)
+
+// PagerDutyConfig sends the events to the PagerDuty Events API v2. The fields of the payload are templates, the
+// custom details are a layout and default to the event itself. Without a severity, the severity of the event is
+// used, and with lifecycle the incidents are resolved on recovery.
+type PagerDutyConfig struct {
+	RoutingKey    string                 `yaml:"routingKey"`
+	Endpoint      string                 `yaml:"endpoint"`
+	Summary       string                 `yaml:"summary"`
+	Source        string                 `yaml:"source"`
+	Severity      string                 `yaml:"severity"`
+	Component     string                 `yaml:"component"`
+	Group         string                 `yaml:"group"`
+	Class         string                 `yaml:"class"`
+	CustomDetails map[string]interface{} `yaml:"customDetails"`
+	DedupKey      string                 `yaml:"dedupKey"`
+	Lifecycle     *LifecycleConfig       `yaml:"lifecycle"`
+	TLS           TLS                    `yaml:"tls"`
+}
+
+type PagerDutySink struct {
+	cfg       *PagerDutyConfig
+	client    *http.Client
+	lifecycle *alertLifecycle
+}
+
+// pagerDutyEvent is the body of the Events API v2
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key,omitempty"`
+	Client      string            `json:"client,omitempty"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string      `json:"summary"`
+	Source        string      `json:"source"`
+	Severity      string      `json:"severity"`
+	Timestamp     string      `json:"timestamp,omitempty"`
+	Component     string      `json:"component,omitempty"`
+	Group         string      `json:"group,omitempty"`
+	Class         string      `json:"class,omitempty"`
+	CustomDetails interface{} `json:"custom_details,omitempty"`
+}
+
+type pagerDutyResponse struct {
+	Status   string `json:"status"`
+	Message  string `json:"message"`
+	DedupKey string `json:"dedup_key"`
+}
+
+func NewPagerDutySink(cfg *PagerDutyConfig) (Sink, error) {
+	if cfg.RoutingKey == "" {
+		return nil, fmt.Errorf("pagerduty routingKey is required")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = pagerDutyEventsURL
+	}
+	if cfg.Summary == "" {
+		cfg.Summary = "{{ .Reason }} on {{ objectRef . }}: {{ .Message }}"
+	}
+	if cfg.Source == "" {
+		cfg.Source = "{{ if .ClusterName }}{{ .ClusterName }}/{{ end }}{{ objectRef . }}"
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	sink := &PagerDutySink{
+		cfg: cfg,
+		client: &http.Client{Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsClientConfig,
+		}},
+	}
+	if cfg.Lifecycle != nil {
+		sink.lifecycle = newAlertLifecycle(sink, cfg.Lifecycle)
+	}
+	return sink, nil
+}
+
+func (p *PagerDutySink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	if p.lifecycle != nil {
+		return p.lifecycle.send(ctx, ev)
+	}
+	_, err := p.openAlert(ctx, ev, "")
+	return err
+}
+
+// openAlert triggers an incident, the dedup key is the key of the lifecycle if no dedup key is configured
+func (p *PagerDutySink) openAlert(ctx context.Context, ev *kube.EnhancedEvent, key string) (string, error) {
+	payload := &pagerDutyPayload{
+		Severity:  ev.GetSeverity(),
+		Timestamp: ev.GetTimestampISO8601(),
+	}
+
+	fields := []struct {
+		text   string
+		target *string
+	}{
+		{p.cfg.Summary, &payload.Summary},
+		{p.cfg.Source, &payload.Source},
+		{p.cfg.Severity, &payload.Severity},
+		{p.cfg.Component, &payload.Component},
+		{p.cfg.Group, &payload.Group},
+		{p.cfg.Class, &payload.Class},
+		{p.cfg.DedupKey, &key},
+	}
+	for _, f := range fields {
+		if f.text == "" {
+			continue
+		}
+		value, err := GetString(ev, f.text)
+		if err != nil {
+			return "", err
+		}
+		*f.target = value
+	}
+	payload.Summary = truncate(pagerDutyMaxSummary, payload.Summary)
+
+	if p.cfg.CustomDetails != nil {
+		details, err := convertLayoutTemplate(p.cfg.CustomDetails, ev)
+		if err != nil {
+			return "", err
+		}
+		payload.CustomDetails = details
+	} else {
+		payload.CustomDetails = ev
+	}
+
+	return p.enqueue(ctx, &pagerDutyEvent{
+		RoutingKey:  p.cfg.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    key,
+		Client:      "kubernetes-event-exporter",
+		Payload:     payload,
+	})
+}
+
+// resolveAlert resolves the incident with the dedup key
+func (p *PagerDutySink) resolveAlert(ctx context.Context, dedupKey string) error {
+	_, err := p.enqueue(ctx, &pagerDutyEvent{
+		RoutingKey:  p.cfg.RoutingKey,
+		EventAction: "resolve",
+		DedupKey:    dedupKey,
+	})
+	return err
+}
+
+// enqueue sends the event and returns the dedup key of the incident
+func (p *PagerDutySink) enqueue(ctx context.Context, event *pagerDutyEvent) (string, error) {
+	reqBody, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.Endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("pagerduty returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	var res pagerDutyResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return "", err
+	}
+	if res.DedupKey != "" {
+		return res.DedupKey, nil
+	}
+	return event.DedupKey, nil
+}
+
+func (p *PagerDutySink) Close() {
+	if p.lifecycle != nil {
+		p.lifecycle.close()
+	}
+	p.client.CloseIdleConnections()
+}
@@ -0,0 +1,102 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newPagerDutyServer(t *testing.T, received *[]map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		*received = append(*received, body)
+
+		if body["routing_key"] != "key" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"invalid event","message":"Event object is invalid"}`))
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"status": "success", "dedup_key": body["dedup_key"].(string)})
+	}))
+}
+
+func TestPagerDutySink_Send(t *testing.T) {
+	var received []map[string]interface{}
+	server := newPagerDutyServer(t, &received)
+	defer server.Close()
+
+	sink, err := NewPagerDutySink(&PagerDutyConfig{
+		RoutingKey:    "key",
+		Endpoint:      server.URL,
+		Component:     "{{ .InvolvedObject.Kind }}",
+		DedupKey:      "{{ .InvolvedObject.UID }}",
+		CustomDetails: map[string]interface{}{"count": "{{ toRaw .Count }}"},
+	})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	ev := newTestEvent()
+	ev.ClusterName = "prod"
+	ev.InvolvedObject.UID = "uid-1"
+	ev.Count = 3
+	assert.NoError(t, sink.Send(context.Background(), ev))
+
+	assert.Len(t, received, 1)
+	assert.Equal(t, "trigger", received[0]["event_action"])
+	assert.Equal(t, "uid-1", received[0]["dedup_key"])
+	payload := received[0]["payload"].(map[string]interface{})
+	assert.Equal(t, "BackOff on Pod/default/nginx: Back-off restarting failed container", payload["summary"])
+	assert.Equal(t, "prod/Pod/default/nginx", payload["source"])
+	assert.Equal(t, "error", payload["severity"])
+	assert.Equal(t, "Pod", payload["component"])
+	assert.Equal(t, map[string]interface{}{"count": float64(3)}, payload["custom_details"])
+}
+
+func TestPagerDutySink_Resolve(t *testing.T) {
+	var received []map[string]interface{}
+	server := newPagerDutyServer(t, &received)
+	defer server.Close()
+
+	sink, err := NewPagerDutySink(&PagerDutyConfig{
+		RoutingKey: "key",
+		Endpoint:   server.URL,
+		Lifecycle:  &LifecycleConfig{},
+	})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	ev := newTestEvent()
+	ev.InvolvedObject.UID = "uid-1"
+	assert.NoError(t, sink.Send(context.Background(), ev))
+	ev.Reason = "Started"
+	assert.NoError(t, sink.Send(context.Background(), ev))
+
+	assert.Len(t, received, 2)
+	assert.Equal(t, "trigger", received[0]["event_action"])
+	assert.Equal(t, "uid-1/BackOff", received[0]["dedup_key"])
+	assert.Equal(t, "resolve", received[1]["event_action"])
+	assert.Equal(t, "uid-1/BackOff", received[1]["dedup_key"])
+	assert.Nil(t, received[1]["payload"])
+}
+
+func TestPagerDutySink_Error(t *testing.T) {
+	var received []map[string]interface{}
+	server := newPagerDutyServer(t, &received)
+	defer server.Close()
+
+	sink, err := NewPagerDutySink(&PagerDutyConfig{RoutingKey: "wrong", Endpoint: server.URL})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	err = sink.Send(context.Background(), newTestEvent())
+	assert.ErrorContains(t, err, "pagerduty returned 400")
+
+	_, err = NewPagerDutySink(&PagerDutyConfig{})
+	assert.Error(t, err)
+}
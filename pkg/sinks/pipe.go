@@ -10,10 +10,11 @@ import (
 )
 
 type PipeConfig struct {
-	Path   string                 `yaml:"path"`
+	Path string `yaml:"path"`
 	// DeDot all labels and annotations in the event. For both the event and the involvedObject
-	DeDot       bool              `yaml:"deDot"`
-	Layout map[string]interface{} `yaml:"layout"`
+	DeDot     bool                   `yaml:"deDot"`
+	Layout    map[string]interface{} `yaml:"layout"`
+	LayoutRef string                 `yaml:"layoutRef"`
 }
 
 func (f *PipeConfig) Validate() error {
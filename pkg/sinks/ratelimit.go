@@ -0,0 +1,168 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitConfig limits the events sent to a receiver with a token bucket. With a key template, for example the
+// channel or the namespace, every key gets its own bucket.
+type RateLimitConfig struct {
+	// Rate is the number of events per second
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+	Key   string  `yaml:"key"`
+}
+
+// rateLimitBucket is the limiter of a key with the events it suppressed since the last summary
+type rateLimitBucket struct {
+	limiter    *rate.Limiter
+	suppressed int32
+	last       *kube.EnhancedEvent
+}
+
+// RateLimiter wraps a sink to limit the rate of the events sent to it. Events over the limit are not sent but
+// counted, and once the limiter has a free token again, one summary is sent in their place. Until the summary is
+// sent, the following events of the key are suppressed too so that the summary comes before them.
+type RateLimiter struct {
+	sink    Sink
+	key     string
+	limit   rate.Limit
+	burst   int
+	now     func() time.Time
+	mu      sync.Mutex
+	sendMu  sync.Mutex
+	buckets map[string]*rateLimitBucket
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+// Validate rejects a negative rate or burst, unset they default to 1
+func (c *RateLimitConfig) Validate() error {
+	if c.Rate < 0 {
+		return fmt.Errorf("rate limit rate must be positive, not %g", c.Rate)
+	}
+	if c.Burst < 0 {
+		return fmt.Errorf("rate limit burst must be positive, not %d", c.Burst)
+	}
+	return nil
+}
+
+func NewRateLimiter(sink Sink, cfg *RateLimitConfig) *RateLimiter {
+	if cfg.Rate == 0 {
+		cfg.Rate = 1
+	}
+	if cfg.Burst == 0 {
+		cfg.Burst = 1
+	}
+
+	r := &RateLimiter{
+		sink:    sink,
+		key:     cfg.Key,
+		limit:   rate.Limit(cfg.Rate),
+		burst:   cfg.Burst,
+		now:     time.Now,
+		buckets: make(map[string]*rateLimitBucket),
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+func (r *RateLimiter) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	key := ""
+	if r.key != "" {
+		var err error
+		if key, err = GetString(ev, r.key); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	b, ok := r.buckets[key]
+	if !ok {
+		b = &rateLimitBucket{limiter: rate.NewLimiter(r.limit, r.burst)}
+		r.buckets[key] = b
+	}
+	if b.suppressed > 0 || !b.limiter.AllowN(r.now(), 1) {
+		b.suppressed++
+		b.last = ev
+		r.mu.Unlock()
+		log.Debug().Str("key", key).Str("event", ev.Message).Msg("Event is rate limited")
+		return nil
+	}
+	r.mu.Unlock()
+
+	return r.send(ctx, ev)
+}
+
+// send serializes the calls to the sink since the summaries are sent from another goroutine
+func (r *RateLimiter) send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+	return r.sink.Send(ctx, ev)
+}
+
+func (r *RateLimiter) run() {
+	defer close(r.doneCh)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.flush(false)
+		case <-r.stopCh:
+			r.flush(true)
+			return
+		}
+	}
+}
+
+// flush sends a summary for the keys whose limiter has a token again, or for all of them if all is true.
+// Buckets that are full and have nothing suppressed are removed so that the keys do not pile up.
+func (r *RateLimiter) flush(all bool) {
+	current := r.now()
+	summaries := make([]*kube.EnhancedEvent, 0)
+
+	r.mu.Lock()
+	for key, b := range r.buckets {
+		if b.suppressed == 0 {
+			if b.limiter.TokensAt(current) >= float64(r.burst) {
+				delete(r.buckets, key)
+			}
+			continue
+		}
+		if !all && !b.limiter.AllowN(current, 1) {
+			continue
+		}
+
+		ev := *b.last
+		ev.Count = b.suppressed
+		ev.Message = fmt.Sprintf("%s (%d events suppressed by rate limit)", ev.Message, b.suppressed)
+		summaries = append(summaries, &ev)
+		b.suppressed = 0
+		b.last = nil
+	}
+	r.mu.Unlock()
+
+	for _, ev := range summaries {
+		if err := r.send(context.Background(), ev); err != nil {
+			log.Debug().Err(err).Str("event", ev.Message).Msg("Cannot send rate limit summary")
+		}
+	}
+}
+
+// Close sends the pending summaries and closes the wrapped sink
+func (r *RateLimiter) Close() {
+	close(r.stopCh)
+	<-r.doneCh
+	r.sink.Close()
+}
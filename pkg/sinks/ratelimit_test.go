@@ -0,0 +1,78 @@
+package sinks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	inner := &InMemory{}
+	r := NewRateLimiter(inner, &RateLimitConfig{Rate: 0.001, Burst: 2, Key: "{{ .Namespace }}"})
+	current := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return current }
+
+	newEvent := func(namespace, message string) *kube.EnhancedEvent {
+		ev := &kube.EnhancedEvent{}
+		ev.Namespace = namespace
+		ev.Message = message
+		return ev
+	}
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, r.Send(context.Background(), newEvent("default", "scheduling failed")))
+	}
+	require.NoError(t, r.Send(context.Background(), newEvent("kube-system", "node not ready")))
+
+	// The burst of the default namespace is used, kube-system has its own bucket
+	require.Len(t, inner.Events, 3)
+	assert.Equal(t, "kube-system", inner.Events[2].Namespace)
+
+	// No token yet, nothing is flushed
+	r.flush(false)
+	require.Len(t, inner.Events, 3)
+
+	// One token is free after 1000 seconds
+	current = current.Add(1000 * time.Second)
+	r.flush(false)
+	require.Len(t, inner.Events, 4)
+	assert.Equal(t, "scheduling failed (3 events suppressed by rate limit)", inner.Events[3].Message)
+	assert.Equal(t, int32(3), inner.Events[3].Count)
+
+	r.Close()
+	assert.Len(t, inner.Events, 4)
+}
+
+func TestRateLimiterFlushOnClose(t *testing.T) {
+	inner := &InMemory{}
+	r := NewRateLimiter(inner, &RateLimitConfig{Rate: 0.001, Burst: 1})
+
+	ev := &kube.EnhancedEvent{}
+	ev.Message = "pulled image"
+	require.NoError(t, r.Send(context.Background(), ev))
+	require.NoError(t, r.Send(context.Background(), ev))
+	require.Len(t, inner.Events, 1)
+
+	r.Close()
+	require.Len(t, inner.Events, 2)
+	assert.Equal(t, "pulled image (1 events suppressed by rate limit)", inner.Events[1].Message)
+}
+
+func TestReceiverConfigRateLimit(t *testing.T) {
+	r := ReceiverConfig{
+		Name:      "limited",
+		InMemory:  &InMemoryConfig{},
+		RateLimit: &RateLimitConfig{Rate: -1},
+	}
+	assert.Error(t, r.Validate())
+
+	r.RateLimit = &RateLimitConfig{Rate: 0.5, Burst: -1}
+	assert.Error(t, r.Validate())
+
+	r.RateLimit = &RateLimitConfig{}
+	assert.NoError(t, r.Validate())
+}
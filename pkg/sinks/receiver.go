@@ -1,10 +1,19 @@
 package sinks
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
 
-// Receiver allows receiving
+// Receiver allows receiving. Silenceable receivers do not get the events muted by a silence.
 type ReceiverConfig struct {
 	Name          string               `yaml:"name"`
+	Silenceable   bool                 `yaml:"silenceable"`
+	Aggregation   *AggregationConfig   `yaml:"aggregation"`
+	RateLimit     *RateLimitConfig     `yaml:"rateLimit"`
+	Redaction     *RedactionConfig     `yaml:"redaction"`
+	Transforms    []TransformConfig    `yaml:"transforms"`
 	InMemory      *InMemoryConfig      `yaml:"inMemory"`
 	Webhook       *WebhookConfig       `yaml:"webhook"`
 	File          *FileConfig          `yaml:"file"`
@@ -25,13 +34,91 @@ type ReceiverConfig struct {
 	BigQuery      *BigQueryConfig      `yaml:"bigquery"`
 	EventBridge   *EventBridgeConfig   `yaml:"eventbridge"`
 	Pipe          *PipeConfig          `yaml:"pipe"`
+	PagerDuty     *PagerDutyConfig     `yaml:"pagerduty"`
+	Loki          *LokiConfig          `yaml:"loki"`
+	Splunk        *SplunkConfig        `yaml:"splunk"`
+	CloudWatch    *CloudWatchConfig    `yaml:"cloudwatch"`
+	Logstash      *LogstashConfig      `yaml:"logstash"`
+	Redis         *RedisConfig         `yaml:"redis"`
+	Email         *EmailConfig         `yaml:"email"`
+	NATS          *NATSConfig          `yaml:"nats"`
+	AMQP          *AMQPConfig          `yaml:"amqp"`
+	MQTT          *MQTTConfig          `yaml:"mqtt"`
 }
 
+// Validate resolves the layout references and compiles the templates of the receiver so that broken templates
+// fail on startup
 func (r *ReceiverConfig) Validate() error {
-	return nil
+	if err := resolveLayoutRefs(r); err != nil {
+		return err
+	}
+	if r.Aggregation != nil {
+		if err := r.Aggregation.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", r.Name, err)
+		}
+	}
+	if r.RateLimit != nil {
+		if err := r.RateLimit.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", r.Name, err)
+		}
+	}
+	if r.Email != nil && r.Email.Digest != nil {
+		if err := r.Email.Digest.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", r.Name, err)
+		}
+	}
+	for _, lifecycle := range r.lifecycles() {
+		if err := lifecycle.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", r.Name, err)
+		}
+	}
+	if r.Redaction != nil {
+		if err := r.Redaction.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", r.Name, err)
+		}
+	}
+	if err := ValidateTransforms(r.Transforms); err != nil {
+		return fmt.Errorf("%s: %w", r.Name, err)
+	}
+	return validateTemplates(r.Name, reflect.ValueOf(r).Elem(), make(map[uintptr]bool))
 }
 
+// lifecycles returns the lifecycle configs of the alerting sinks of the receiver
+func (r *ReceiverConfig) lifecycles() []*LifecycleConfig {
+	var lifecycles []*LifecycleConfig
+	if r.Opsgenie != nil && r.Opsgenie.Lifecycle != nil {
+		lifecycles = append(lifecycles, r.Opsgenie.Lifecycle)
+	}
+	if r.Opscenter != nil && r.Opscenter.Lifecycle != nil {
+		lifecycles = append(lifecycles, r.Opscenter.Lifecycle)
+	}
+	if r.PagerDuty != nil && r.PagerDuty.Lifecycle != nil {
+		lifecycles = append(lifecycles, r.PagerDuty.Lifecycle)
+	}
+	return lifecycles
+}
+
+// GetSink creates the sink of the receiver, wrapped in a rate limiter, an aggregator and a transformer if they are
+// enabled. Events are transformed first, then aggregated and rate limited.
 func (r *ReceiverConfig) GetSink() (Sink, error) {
+	sink, err := r.getSink()
+	if err != nil {
+		return nil, err
+	}
+
+	if r.RateLimit != nil {
+		sink = NewRateLimiter(sink, r.RateLimit)
+	}
+	if r.Aggregation != nil {
+		sink = NewAggregator(sink, r.Aggregation)
+	}
+	if len(r.Transforms) > 0 {
+		sink = NewTransformer(sink, r.Transforms)
+	}
+	return sink, nil
+}
+
+func (r *ReceiverConfig) getSink() (Sink, error) {
 	if r.InMemory != nil {
 		// This reference is used for test purposes to count the events in the sink.
 		// It should not be used in production since it will only cause memory leak and (b)OOM
@@ -117,5 +204,45 @@ func (r *ReceiverConfig) GetSink() (Sink, error) {
 		return NewEventBridgeSink(r.EventBridge)
 	}
 
+	if r.PagerDuty != nil {
+		return NewPagerDutySink(r.PagerDuty)
+	}
+
+	if r.Loki != nil {
+		return NewLokiSink(r.Loki)
+	}
+
+	if r.Splunk != nil {
+		return NewSplunkSink(r.Splunk)
+	}
+
+	if r.CloudWatch != nil {
+		return NewCloudWatchSink(r.CloudWatch)
+	}
+
+	if r.Logstash != nil {
+		return NewLogstashSink(r.Logstash)
+	}
+
+	if r.Redis != nil {
+		return NewRedisSink(r.Redis)
+	}
+
+	if r.Email != nil {
+		return NewEmailSink(r.Email)
+	}
+
+	if r.NATS != nil {
+		return NewNATSSink(r.NATS)
+	}
+
+	if r.AMQP != nil {
+		return NewAMQPSink(r.AMQP)
+	}
+
+	if r.MQTT != nil {
+		return NewMQTTSink(r.MQTT)
+	}
+
 	return nil, errors.New("unknown sink")
 }
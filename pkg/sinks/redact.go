@@ -0,0 +1,287 @@
+package sinks
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+)
+
+const defaultRedactionReplacement = "[REDACTED]"
+
+// RedactionConfig removes sensitive data from the events before they are sent. It can be set globally and for each
+// receiver, the global one is applied first.
+type RedactionConfig struct {
+	// Patterns are replaced in the message, the annotation values and the string values of the transformed fields
+	Patterns []RedactionPattern `yaml:"patterns"`
+	// DropAnnotations are regular expressions of the annotation keys to remove
+	DropAnnotations []string `yaml:"dropAnnotations"`
+	// HashFields are replaced with their SHA-256, e.g. message, involvedObject.name or labels.app
+	HashFields []string `yaml:"hashFields"`
+
+	patterns        []*regexp.Regexp
+	dropAnnotations []*regexp.Regexp
+}
+
+// RedactionPattern replaces the matches of a regular expression, the replacement can refer to groups like $1
+type RedactionPattern struct {
+	Regex       string `yaml:"regex"`
+	Replacement string `yaml:"replacement"`
+}
+
+// hashableFields are the fields that can be hashed, the ones ending with a dot are maps and need a key
+var hashableFields = []string{
+	"message", "reason", "namespace", "name", "involvedObject.name", "involvedObject.namespace", "source.host",
+	"labels.", "annotations.", "involvedObject.labels.", "involvedObject.annotations.", "fields.",
+}
+
+// Validate compiles the regular expressions and checks the hashed fields
+func (c *RedactionConfig) Validate() error {
+	c.patterns = make([]*regexp.Regexp, len(c.Patterns))
+	for i, p := range c.Patterns {
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return fmt.Errorf("redaction pattern %d: %w", i, err)
+		}
+		c.patterns[i] = re
+	}
+
+	c.dropAnnotations = make([]*regexp.Regexp, len(c.DropAnnotations))
+	for i, expr := range c.DropAnnotations {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("redaction dropAnnotations %d: %w", i, err)
+		}
+		c.dropAnnotations[i] = re
+	}
+
+	for _, field := range c.HashFields {
+		if !isHashableField(field) {
+			return fmt.Errorf("redaction cannot hash field %q", field)
+		}
+	}
+	return nil
+}
+
+func isHashableField(field string) bool {
+	for _, f := range hashableFields {
+		if strings.HasSuffix(f, ".") {
+			if strings.HasPrefix(field, f) && len(field) > len(f) {
+				return true
+			}
+		} else if field == f {
+			return true
+		}
+	}
+	return false
+}
+
+// redact applies the config to the event in place and returns the number of redactions
+func (c *RedactionConfig) redact(ev *kube.EnhancedEvent) int {
+	n := 0
+	for _, field := range c.HashFields {
+		if hashField(ev, field) {
+			n++
+		}
+	}
+
+	for i, re := range c.patterns {
+		replacement := c.Patterns[i].Replacement
+		if replacement == "" {
+			replacement = defaultRedactionReplacement
+		}
+		ev.Message, n = replaceCounting(re, ev.Message, replacement, n)
+		for _, m := range []map[string]string{ev.Annotations, ev.InvolvedObject.Annotations} {
+			for k, v := range m {
+				m[k], n = replaceCounting(re, v, replacement, n)
+			}
+		}
+		// The route transforms run before the redaction, so the values they copied to the fields are redacted too
+		for k, v := range ev.Fields {
+			ev.Fields[k], n = replaceFieldValue(re, v, replacement, n)
+		}
+	}
+
+	for _, re := range c.dropAnnotations {
+		for _, m := range []map[string]string{ev.Annotations, ev.InvolvedObject.Annotations} {
+			for k := range m {
+				if re.MatchString(k) {
+					delete(m, k)
+					n++
+				}
+			}
+		}
+	}
+	return n
+}
+
+func replaceCounting(re *regexp.Regexp, s, replacement string, n int) (string, int) {
+	matches := len(re.FindAllStringIndex(s, -1))
+	if matches == 0 {
+		return s, n
+	}
+	return re.ReplaceAllString(s, replacement), n + matches
+}
+
+// replaceFieldValue replaces the matches in the strings of a field value, including the nested ones
+func replaceFieldValue(re *regexp.Regexp, v interface{}, replacement string, n int) (interface{}, int) {
+	switch val := v.(type) {
+	case string:
+		return replaceCounting(re, val, replacement, n)
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k], n = replaceFieldValue(re, item, replacement, n)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i], n = replaceFieldValue(re, item, replacement, n)
+		}
+	}
+	return v, n
+}
+
+// hashField replaces the field with its hash, it returns false if the field is empty
+func hashField(ev *kube.EnhancedEvent, field string) bool {
+	var target *string
+	switch field {
+	case "message":
+		target = &ev.Message
+	case "reason":
+		target = &ev.Reason
+	case "namespace":
+		target = &ev.Namespace
+	case "name":
+		target = &ev.Name
+	case "involvedObject.name":
+		target = &ev.InvolvedObject.Name
+	case "involvedObject.namespace":
+		target = &ev.InvolvedObject.Namespace
+	case "source.host":
+		target = &ev.Source.Host
+	default:
+		if strings.HasPrefix(field, "fields.") {
+			key := strings.TrimPrefix(field, "fields.")
+			v, ok := ev.Fields[key].(string)
+			if !ok || v == "" {
+				return false
+			}
+			ev.Fields[key] = hashValue(v)
+			return true
+		}
+
+		var m map[string]string
+		var key string
+		switch {
+		case strings.HasPrefix(field, "involvedObject.labels."):
+			m, key = ev.InvolvedObject.Labels, strings.TrimPrefix(field, "involvedObject.labels.")
+		case strings.HasPrefix(field, "involvedObject.annotations."):
+			m, key = ev.InvolvedObject.Annotations, strings.TrimPrefix(field, "involvedObject.annotations.")
+		case strings.HasPrefix(field, "labels."):
+			m, key = ev.Labels, strings.TrimPrefix(field, "labels.")
+		case strings.HasPrefix(field, "annotations."):
+			m, key = ev.Annotations, strings.TrimPrefix(field, "annotations.")
+		}
+		v, ok := m[key]
+		if !ok || v == "" {
+			return false
+		}
+		m[key] = hashValue(v)
+		return true
+	}
+
+	if *target == "" {
+		return false
+	}
+	*target = hashValue(*target)
+	return true
+}
+
+func hashValue(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Redactor wraps a sink to redact the events before they are sent to it
+type Redactor struct {
+	sink       Sink
+	configs    []*RedactionConfig
+	onRedacted func(n int)
+}
+
+// NewRedactor returns the sink as is if none of the configs is set. onRedacted is called with the number of
+// redactions done on an event, if there are any.
+func NewRedactor(sink Sink, configs []*RedactionConfig, onRedacted func(n int)) (Sink, error) {
+	set := make([]*RedactionConfig, 0, len(configs))
+	for _, c := range configs {
+		if c == nil {
+			continue
+		}
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+		set = append(set, c)
+	}
+	if len(set) == 0 {
+		return sink, nil
+	}
+	return &Redactor{sink: sink, configs: set, onRedacted: onRedacted}, nil
+}
+
+func (r *Redactor) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	// The maps are shared with the events sent to the other receivers
+	c := *ev
+	c.Labels = copyStringMap(ev.Labels)
+	c.Annotations = copyStringMap(ev.Annotations)
+	c.InvolvedObject.Labels = copyStringMap(ev.InvolvedObject.Labels)
+	c.InvolvedObject.Annotations = copyStringMap(ev.InvolvedObject.Annotations)
+	if ev.Fields != nil {
+		c.Fields = copyFieldValue(ev.Fields).(map[string]interface{})
+	}
+
+	n := 0
+	for _, cfg := range r.configs {
+		n += cfg.redact(&c)
+	}
+	if n > 0 && r.onRedacted != nil {
+		r.onRedacted(n)
+	}
+	return r.sink.Send(ctx, &c)
+}
+
+func (r *Redactor) Close() {
+	r.sink.Close()
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// copyFieldValue copies the maps and slices of a field value so that redacting it does not change the original
+func copyFieldValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			c[k] = copyFieldValue(item)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(val))
+		for i, item := range val {
+			c[i] = copyFieldValue(item)
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,95 @@
+package sinks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor(t *testing.T) {
+	global := &RedactionConfig{
+		Patterns: []RedactionPattern{{Regex: `password=\S+`}},
+	}
+	receiver := &RedactionConfig{
+		Patterns:        []RedactionPattern{{Regex: `(postgres://)[^@]+@`, Replacement: "${1}***@"}},
+		DropAnnotations: []string{`^kubectl\.kubernetes\.io/`},
+		HashFields:      []string{"involvedObject.name", "involvedObject.labels.owner", "source.host"},
+	}
+
+	inMemory := &InMemory{}
+	redactions := 0
+	sink, err := NewRedactor(inMemory, []*RedactionConfig{global, receiver}, func(n int) { redactions += n })
+	require.NoError(t, err)
+
+	ev := &kube.EnhancedEvent{}
+	ev.Message = "connecting to postgres://admin:secret@db:5432 with password=hunter2"
+	ev.Annotations = map[string]string{
+		"kubectl.kubernetes.io/last-applied-configuration": "{}",
+		"note": "password=hunter2",
+	}
+	ev.InvolvedObject.Name = "nginx"
+	ev.InvolvedObject.Labels = map[string]string{"owner": "alice", "app": "web"}
+
+	require.NoError(t, sink.Send(context.Background(), ev))
+	require.Len(t, inMemory.Events, 1)
+	res := inMemory.Events[0]
+
+	require.Equal(t, "connecting to postgres://***@db:5432 with [REDACTED]", res.Message)
+	require.Equal(t, map[string]string{"note": "[REDACTED]"}, res.Annotations)
+	require.Equal(t, hashValue("nginx"), res.InvolvedObject.Name)
+	require.Equal(t, hashValue("alice"), res.InvolvedObject.Labels["owner"])
+	require.Equal(t, "web", res.InvolvedObject.Labels["app"])
+	// The source host is empty so there is nothing to hash
+	require.Equal(t, "", res.Source.Host)
+	require.Equal(t, 6, redactions)
+
+	// The event seen by the other receivers is not changed
+	require.Equal(t, "nginx", ev.InvolvedObject.Name)
+	require.Equal(t, "alice", ev.InvolvedObject.Labels["owner"])
+	require.Len(t, ev.Annotations, 2)
+}
+
+func TestRedactor_TransformedFields(t *testing.T) {
+	// A route transform copies the message to a field before the receiver redacts the event
+	transforms := []TransformConfig{{Copy: map[string]string{"message": "details.text"}}, {Set: map[string]interface{}{"owner": "alice"}}}
+	require.NoError(t, ValidateTransforms(transforms))
+
+	ev := &kube.EnhancedEvent{}
+	ev.Message = "login with password=hunter2"
+	transformed, err := ApplyTransforms(ev, transforms)
+	require.NoError(t, err)
+
+	inMemory := &InMemory{}
+	sink, err := NewRedactor(inMemory, []*RedactionConfig{{
+		Patterns:   []RedactionPattern{{Regex: `password=\S+`}},
+		HashFields: []string{"fields.owner"},
+	}}, nil)
+	require.NoError(t, err)
+	require.NoError(t, sink.Send(context.Background(), transformed))
+
+	res := inMemory.Events[0]
+	require.Equal(t, "login with [REDACTED]", res.Message)
+	require.Equal(t, map[string]interface{}{"text": "login with [REDACTED]"}, res.Fields["details"])
+	require.Equal(t, hashValue("alice"), res.Fields["owner"])
+
+	// The fields seen by the other receivers are not changed
+	require.Equal(t, map[string]interface{}{"text": "login with password=hunter2"}, transformed.Fields["details"])
+	require.Equal(t, "alice", transformed.Fields["owner"])
+}
+
+func TestNewRedactor_WithoutConfig(t *testing.T) {
+	inMemory := &InMemory{}
+	sink, err := NewRedactor(inMemory, []*RedactionConfig{nil, nil}, nil)
+	require.NoError(t, err)
+	require.Equal(t, inMemory, sink)
+}
+
+func TestRedactionConfig_Validate(t *testing.T) {
+	require.Error(t, (&RedactionConfig{Patterns: []RedactionPattern{{Regex: "("}}}).Validate())
+	require.Error(t, (&RedactionConfig{DropAnnotations: []string{"["}}).Validate())
+	require.Error(t, (&RedactionConfig{HashFields: []string{"labels."}}).Validate())
+	require.Error(t, (&RedactionConfig{HashFields: []string{"uid"}}).Validate())
+	require.NoError(t, (&RedactionConfig{HashFields: []string{"labels.app", "message"}}).Validate())
+}
@@ -1 +1,127 @@
 package sinks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+)
+
+// RedisConfig adds the events to a Redis stream with XADD or publishes them to a channel with PUBLISH. The stream
+// and the channel are templates. Mode is standalone, sentinel (with the master name) or cluster, the addresses are
+// the server, the sentinels or the seed nodes of the cluster.
+type RedisConfig struct {
+	Mode             string   `yaml:"mode"`
+	Addresses        []string `yaml:"addresses"`
+	MasterName       string   `yaml:"masterName"`
+	SentinelPassword string   `yaml:"sentinelPassword"`
+	Username         string   `yaml:"username"`
+	Password         string   `yaml:"password"`
+	DB               int      `yaml:"db"`
+	// Command is xadd or publish
+	Command string `yaml:"command"`
+	Stream  string `yaml:"stream"`
+	// MaxLen trims the stream to about MaxLen entries, exactly with ExactMaxLen
+	MaxLen      int64                  `yaml:"maxLen"`
+	ExactMaxLen bool                   `yaml:"exactMaxLen"`
+	Channel     string                 `yaml:"channel"`
+	Layout      map[string]interface{} `yaml:"layout"`
+	LayoutRef   string                 `yaml:"layoutRef"`
+	TLS         *TLS                   `yaml:"tls"`
+}
+
+type RedisSink struct {
+	cfg    *RedisConfig
+	client redis.UniversalClient
+}
+
+func NewRedisSink(cfg *RedisConfig) (Sink, error) {
+	if len(cfg.Addresses) == 0 {
+		return nil, fmt.Errorf("redis addresses are required")
+	}
+
+	switch cfg.Command {
+	case "":
+		cfg.Command = "xadd"
+	case "xadd", "publish":
+	default:
+		return nil, fmt.Errorf("redis command must be xadd or publish, not %q", cfg.Command)
+	}
+	if cfg.Command == "xadd" && cfg.Stream == "" {
+		cfg.Stream = "kube-events"
+	}
+	if cfg.Command == "publish" && cfg.Channel == "" {
+		return nil, fmt.Errorf("redis channel is required for publish")
+	}
+
+	client, err := newRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisSink{cfg: cfg, client: client}, nil
+}
+
+func newRedisClient(cfg *RedisConfig) (redis.UniversalClient, error) {
+	opts := &redis.UniversalOptions{
+		Addrs:            cfg.Addresses,
+		MasterName:       cfg.MasterName,
+		SentinelPassword: cfg.SentinelPassword,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		DB:               cfg.DB,
+	}
+	if cfg.TLS != nil {
+		tlsConfig, err := setupTLS(cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup TLS: %w", err)
+		}
+		opts.TLSConfig = tlsConfig
+	}
+
+	switch cfg.Mode {
+	case "", "standalone":
+		if len(cfg.Addresses) != 1 {
+			return nil, fmt.Errorf("redis standalone mode needs exactly one address")
+		}
+		return redis.NewClient(opts.Simple()), nil
+	case "sentinel":
+		if cfg.MasterName == "" {
+			return nil, fmt.Errorf("redis masterName is required for sentinel")
+		}
+		return redis.NewFailoverClient(opts.Failover()), nil
+	case "cluster":
+		return redis.NewClusterClient(opts.Cluster()), nil
+	}
+	return nil, fmt.Errorf("redis mode must be standalone, sentinel or cluster, not %q", cfg.Mode)
+}
+
+func (r *RedisSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	event, err := serializeEventWithLayout(r.cfg.Layout, ev)
+	if err != nil {
+		return err
+	}
+
+	if r.cfg.Command == "publish" {
+		channel, err := GetString(ev, r.cfg.Channel)
+		if err != nil {
+			return err
+		}
+		return r.client.Publish(ctx, channel, event).Err()
+	}
+
+	stream, err := GetString(ev, r.cfg.Stream)
+	if err != nil {
+		return err
+	}
+	return r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: r.cfg.MaxLen,
+		Approx: !r.cfg.ExactMaxLen,
+		Values: []interface{}{"event", event},
+	}).Err()
+}
+
+func (r *RedisSink) Close() {
+	_ = r.client.Close()
+}
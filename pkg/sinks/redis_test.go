@@ -0,0 +1,83 @@
+package sinks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisSink_XAdd(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireUserAuth("exporter", "secret")
+
+	sink, err := NewRedisSink(&RedisConfig{
+		Addresses: []string{server.Addr()},
+		Username:  "exporter",
+		Password:  "secret",
+		Stream:    "events:{{ .InvolvedObject.Namespace }}",
+		MaxLen:    2,
+		Layout:    map[string]interface{}{"reason": "{{ .Reason }}"},
+	})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, sink.Send(context.Background(), newTestEvent()))
+	}
+	ev := newTestEvent()
+	ev.InvolvedObject.Namespace = "kube-system"
+	assert.NoError(t, sink.Send(context.Background(), ev))
+
+	entries, err := server.Stream("events:default")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, []string{"event", `{"reason":"BackOff"}`}, entries[0].Values)
+
+	entries, err = server.Stream("events:kube-system")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestRedisSink_Publish(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	sink, err := NewRedisSink(&RedisConfig{
+		Addresses: []string{server.Addr()},
+		Command:   "publish",
+		Channel:   "events.{{ .InvolvedObject.Namespace }}",
+		Layout:    map[string]interface{}{"reason": "{{ .Reason }}"},
+	})
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	subscriber := server.NewSubscriber()
+	defer subscriber.Close()
+	subscriber.Subscribe("events.default")
+
+	// The messages of miniredis are not buffered, they are read while the event is published
+	messages := make(chan string, 1)
+	go func() {
+		messages <- (<-subscriber.Messages()).Message
+	}()
+	assert.NoError(t, sink.Send(context.Background(), newTestEvent()))
+	assert.Equal(t, `{"reason":"BackOff"}`, <-messages)
+}
+
+func TestRedisSink_Invalid(t *testing.T) {
+	_, err := NewRedisSink(&RedisConfig{})
+	assert.Error(t, err)
+
+	_, err = NewRedisSink(&RedisConfig{Addresses: []string{"a:6379"}, Command: "set"})
+	assert.Error(t, err)
+
+	_, err = NewRedisSink(&RedisConfig{Addresses: []string{"a:6379"}, Command: "publish"})
+	assert.Error(t, err)
+
+	_, err = NewRedisSink(&RedisConfig{Addresses: []string{"a:26379"}, Mode: "sentinel"})
+	assert.Error(t, err)
+
+	_, err = NewRedisSink(&RedisConfig{Addresses: []string{"a:6379", "b:6379"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,17 @@
+package sinks
+
+import (
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+)
+
+// newTestEvent returns a Warning event of the nginx pod in the default namespace, the tests change what they need
+func newTestEvent() *kube.EnhancedEvent {
+	ev := &kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.Reason = "BackOff"
+	ev.Message = "Back-off restarting failed container"
+	ev.InvolvedObject.Kind = "Pod"
+	ev.InvolvedObject.Namespace = "default"
+	ev.InvolvedObject.Name = "nginx"
+	return ev
+}
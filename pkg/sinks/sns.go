@@ -9,9 +9,10 @@ import (
 )
 
 type SNSConfig struct {
-	TopicARN string                 `yaml:"topicARN"`
-	Region   string                 `yaml:"region"`
-	Layout   map[string]interface{} `yaml:"layout"`
+	TopicARN  string                 `yaml:"topicARN"`
+	Region    string                 `yaml:"region"`
+	Layout    map[string]interface{} `yaml:"layout"`
+	LayoutRef string                 `yaml:"layoutRef"`
 }
 
 type SNSSink struct {
@@ -1 +1,309 @@
 package sinks
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resmoio/kubernetes-event-exporter/pkg/batch"
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	splunkCollectorPath = "/services/collector"
+	splunkEventPath     = splunkCollectorPath + "/event"
+	splunkAckPath       = splunkCollectorPath + "/ack"
+)
+
+// SplunkConfig sends the events to the HTTP Event Collector of Splunk in batches. Index, source, sourcetype and host
+// are templates, the event is the layout or the event itself. With ack, a batch is only done once the indexers
+// acknowledged it, which needs indexer acknowledgment enabled on the token.
+type SplunkConfig struct {
+	URL               string                 `yaml:"url"`
+	Token             string                 `yaml:"token"`
+	Index             string                 `yaml:"index"`
+	Source            string                 `yaml:"source"`
+	Sourcetype        string                 `yaml:"sourcetype"`
+	Host              string                 `yaml:"host"`
+	Layout            map[string]interface{} `yaml:"layout"`
+	LayoutRef         string                 `yaml:"layoutRef"`
+	Gzip              bool                   `yaml:"gzip"`
+	Ack               bool                   `yaml:"ack"`
+	Channel           string                 `yaml:"channel"`
+	AckTimeoutSeconds int                    `yaml:"ackTimeoutSeconds"`
+	TLS               TLS                    `yaml:"tls"`
+	BatchSize         int                    `yaml:"batchSize"`
+	MaxRetries        int                    `yaml:"maxRetries"`
+	IntervalSeconds   int                    `yaml:"intervalSeconds"`
+	TimeoutSeconds    int                    `yaml:"timeoutSeconds"`
+}
+
+// splunkEvent is an event of the HEC event endpoint, time is in seconds with milliseconds
+type splunkEvent struct {
+	Time       *json.Number `json:"time,omitempty"`
+	Host       string       `json:"host,omitempty"`
+	Source     string       `json:"source,omitempty"`
+	Sourcetype string       `json:"sourcetype,omitempty"`
+	Index      string       `json:"index,omitempty"`
+	Event      interface{}  `json:"event"`
+}
+
+type splunkResponse struct {
+	Text  string `json:"text"`
+	Code  int    `json:"code"`
+	AckID *int64 `json:"ackId"`
+}
+
+type SplunkSink struct {
+	cfg         *SplunkConfig
+	endpoint    string
+	ackEndpoint string
+	client      *http.Client
+	batchWriter *batch.Writer
+	ackInterval time.Duration
+}
+
+func NewSplunkSink(cfg *SplunkConfig) (Sink, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("splunk url is required")
+	}
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("splunk token is required")
+	}
+	endpoint, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	// The path of the URL is kept as a prefix, e.g. for a reverse proxy, with or without the collector path
+	prefix := strings.TrimSuffix(endpoint.Path, "/")
+	prefix = strings.TrimSuffix(prefix, splunkEventPath)
+	prefix = strings.TrimSuffix(prefix, splunkCollectorPath)
+	endpoint.RawPath = ""
+	ackEndpoint := *endpoint
+	endpoint.Path = path.Join("/", prefix, splunkEventPath)
+	ackEndpoint.Path = path.Join("/", prefix, splunkAckPath)
+
+	if cfg.Sourcetype == "" {
+		cfg.Sourcetype = "kube:event"
+	}
+	// Splunk only acknowledges the requests of a channel
+	if cfg.Ack && cfg.Channel == "" {
+		cfg.Channel = uuid.NewString()
+	}
+	if cfg.AckTimeoutSeconds == 0 {
+		cfg.AckTimeoutSeconds = 60
+	}
+
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.IntervalSeconds == 0 {
+		cfg.IntervalSeconds = 5
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 30
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	s := &SplunkSink{
+		cfg:         cfg,
+		endpoint:    endpoint.String(),
+		ackEndpoint: ackEndpoint.String(),
+		client: &http.Client{
+			Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsClientConfig,
+			},
+		},
+		ackInterval: time.Second,
+	}
+
+	s.batchWriter = batch.NewWriter(
+		batch.WriterConfig{
+			BatchSize:  cfg.BatchSize,
+			MaxRetries: cfg.MaxRetries,
+			Interval:   time.Duration(cfg.IntervalSeconds) * time.Second,
+			Timeout:    time.Duration(cfg.TimeoutSeconds) * time.Second,
+		},
+		s.handleBatch,
+	)
+	s.batchWriter.Start()
+	return s, nil
+}
+
+func (s *SplunkSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	event := &splunkEvent{Event: ev}
+	if s.cfg.Layout != nil {
+		res, err := convertLayoutTemplate(s.cfg.Layout, ev)
+		if err != nil {
+			return err
+		}
+		event.Event = res
+	}
+
+	fields := []struct {
+		text   string
+		target *string
+	}{
+		{s.cfg.Index, &event.Index},
+		{s.cfg.Source, &event.Source},
+		{s.cfg.Sourcetype, &event.Sourcetype},
+		{s.cfg.Host, &event.Host},
+	}
+	for _, f := range fields {
+		if f.text == "" {
+			continue
+		}
+		value, err := GetString(ev, f.text)
+		if err != nil {
+			return err
+		}
+		*f.target = value
+	}
+
+	// Without a timestamp, Splunk uses the time it receives the event
+	if !ev.FirstTimestamp.IsZero() || !ev.EventTime.IsZero() {
+		ms := ev.GetTimestampMs()
+		ts := json.Number(strconv.FormatFloat(float64(ms)/1000, 'f', 3, 64))
+		event.Time = &ts
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.batchWriter.Submit(body)
+	return nil
+}
+
+func (s *SplunkSink) handleBatch(ctx context.Context, items []interface{}) []bool {
+	res := make([]bool, len(items))
+	err := s.post(ctx, items)
+	if err != nil {
+		log.Error().Err(err).Int("events", len(items)).Msg("Cannot send events to Splunk")
+	}
+	for i := range res {
+		res[i] = err == nil
+	}
+	return res
+}
+
+// post sends the events in one request, HEC takes the events one after the other in the body
+func (s *SplunkSink) post(ctx context.Context, items []interface{}) error {
+	var buf bytes.Buffer
+	for _, item := range items {
+		buf.Write(item.([]byte))
+		buf.WriteByte('\n')
+	}
+
+	body := buf.Bytes()
+	if s.cfg.Gzip {
+		var compressed bytes.Buffer
+		w := gzip.NewWriter(&compressed)
+		if _, err := w.Write(body); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		body = compressed.Bytes()
+	}
+
+	var res splunkResponse
+	err := s.do(ctx, s.endpoint, body, &res)
+	if err != nil {
+		return err
+	}
+
+	if s.cfg.Ack {
+		if res.AckID == nil {
+			return fmt.Errorf("splunk did not return an ackId, indexer acknowledgment is not enabled for the token")
+		}
+		return s.waitAck(ctx, *res.AckID)
+	}
+	return nil
+}
+
+// waitAck polls the acknowledgment of the request until the indexers acknowledge it or the ack timeout
+func (s *SplunkSink) waitAck(ctx context.Context, ackID int64) error {
+	body, err := json.Marshal(map[string][]int64{"acks": {ackID}})
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(time.Duration(s.cfg.AckTimeoutSeconds) * time.Second)
+	for {
+		var res struct {
+			Acks map[string]bool `json:"acks"`
+		}
+		if err := s.do(ctx, s.ackEndpoint, body, &res); err != nil {
+			return err
+		}
+		if res.Acks[strconv.FormatInt(ackID, 10)] {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("splunk did not acknowledge %d in %d seconds", ackID, s.cfg.AckTimeoutSeconds)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.ackInterval):
+		}
+	}
+}
+
+func (s *SplunkSink) do(ctx context.Context, endpoint string, body []byte, res interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Splunk "+s.cfg.Token)
+	req.Header.Set("Content-Type", "application/json")
+	if s.cfg.Gzip && endpoint == s.endpoint {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if s.cfg.Channel != "" {
+		req.Header.Set("X-Splunk-Request-Channel", s.cfg.Channel)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("splunk returned %d: %s", resp.StatusCode, string(respBody))
+	}
+	return json.Unmarshal(respBody, res)
+}
+
+func (s *SplunkSink) Close() {
+	s.batchWriter.Stop()
+	s.client.CloseIdleConnections()
+}
@@ -0,0 +1,139 @@
+package sinks
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSplunkSink_Send(t *testing.T) {
+	var events []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, splunkEventPath, r.URL.Path)
+		assert.Equal(t, "Splunk token", r.Header.Get("Authorization"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+
+		body, err := gzip.NewReader(r.Body)
+		assert.NoError(t, err)
+		decoder := json.NewDecoder(body)
+		for {
+			var event map[string]interface{}
+			if err := decoder.Decode(&event); err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+			events = append(events, event)
+		}
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer server.Close()
+
+	sink, err := NewSplunkSink(&SplunkConfig{
+		URL:    server.URL,
+		Token:  "token",
+		Index:  "k8s-{{ .ClusterName }}",
+		Host:   "{{ .ClusterName }}",
+		Source: "{{ .InvolvedObject.Namespace }}",
+		Layout: map[string]interface{}{"message": "{{ .Message }}"},
+		Gzip:   true,
+	})
+	assert.NoError(t, err)
+
+	ev := newTestEvent()
+	ev.ClusterName = "prod"
+	ev.FirstTimestamp = metav1.NewTime(time.UnixMilli(1600000000123))
+	assert.NoError(t, sink.Send(context.Background(), ev))
+	ev.InvolvedObject.Namespace = "kube-system"
+	assert.NoError(t, sink.Send(context.Background(), ev))
+	sink.Close()
+
+	assert.Equal(t, []map[string]interface{}{
+		{
+			"time":       1600000000.123,
+			"host":       "prod",
+			"source":     "default",
+			"sourcetype": "kube:event",
+			"index":      "k8s-prod",
+			"event":      map[string]interface{}{"message": "Back-off restarting failed container"},
+		},
+		{
+			"time":       1600000000.123,
+			"host":       "prod",
+			"source":     "kube-system",
+			"sourcetype": "kube:event",
+			"index":      "k8s-prod",
+			"event":      map[string]interface{}{"message": "Back-off restarting failed container"},
+		},
+	}, events)
+}
+
+func TestSplunkSink_Ack(t *testing.T) {
+	var polls int
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "channel-1", r.Header.Get("X-Splunk-Request-Channel"))
+		switch r.URL.Path {
+		case splunkEventPath:
+			requests++
+			w.Write([]byte(`{"text":"Success","code":0,"ackId":7}`))
+		case splunkAckPath:
+			var body map[string][]int64
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, []int64{7}, body["acks"])
+			polls++
+			json.NewEncoder(w).Encode(map[string]interface{}{"acks": map[string]bool{"7": polls > 1}})
+		}
+	}))
+	defer server.Close()
+
+	sink, err := NewSplunkSink(&SplunkConfig{
+		URL:     server.URL,
+		Token:   "token",
+		Ack:     true,
+		Channel: "channel-1",
+	})
+	assert.NoError(t, err)
+	sink.(*SplunkSink).ackInterval = 10 * time.Millisecond
+
+	assert.NoError(t, sink.Send(context.Background(), newTestEvent()))
+	sink.Close()
+
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, 2, polls)
+}
+
+func TestSplunkSink_Invalid(t *testing.T) {
+	_, err := NewSplunkSink(&SplunkConfig{Token: "token"})
+	assert.Error(t, err)
+
+	_, err = NewSplunkSink(&SplunkConfig{URL: "https://splunk:8088"})
+	assert.Error(t, err)
+}
+
+func TestSplunkSink_URLPrefix(t *testing.T) {
+	for _, u := range []string{
+		"https://proxy.example.com/splunk",
+		"https://proxy.example.com/splunk/",
+		"https://proxy.example.com/splunk/services/collector",
+		"https://proxy.example.com/splunk/services/collector/event",
+	} {
+		sink, err := NewSplunkSink(&SplunkConfig{URL: u, Token: "token"})
+		assert.NoError(t, err)
+		assert.Equal(t, "https://proxy.example.com/splunk/services/collector/event", sink.(*SplunkSink).endpoint, u)
+		assert.Equal(t, "https://proxy.example.com/splunk/services/collector/ack", sink.(*SplunkSink).ackEndpoint, u)
+		sink.Close()
+	}
+
+	sink, err := NewSplunkSink(&SplunkConfig{URL: "https://splunk.example.com:8088", Token: "token"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://splunk.example.com:8088/services/collector/event", sink.(*SplunkSink).endpoint)
+	sink.Close()
+}
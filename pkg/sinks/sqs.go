@@ -12,6 +12,7 @@ type SQSConfig struct {
 	QueueName string                 `yaml:"queueName"`
 	Region    string                 `yaml:"region"`
 	Layout    map[string]interface{} `yaml:"layout"`
+	LayoutRef string                 `yaml:"layoutRef"`
 }
 
 type SQSSink struct {
@@ -12,8 +12,9 @@ import (
 
 type StdoutConfig struct {
 	// DeDot all labels and annotations in the event. For both the event and the involvedObject
-	DeDot       bool              `yaml:"deDot"`
-	Layout map[string]interface{} `yaml:"layout"`
+	DeDot     bool                   `yaml:"deDot"`
+	Layout    map[string]interface{} `yaml:"layout"`
+	LayoutRef string                 `yaml:"layoutRef"`
 }
 
 func (f *StdoutConfig) Validate() error {
@@ -7,14 +7,18 @@ import (
 	"log/syslog"
 )
 
+// SyslogConfig writes the events to a syslog server, with UseSeverity the syslog severity follows the severity of
+// the event
 type SyslogConfig struct {
-	Network string `yaml:"network"`
-	Address string `yaml:"address"`
-	Tag     string `yaml:"tag"`
+	Network     string `yaml:"network"`
+	Address     string `yaml:"address"`
+	Tag         string `yaml:"tag"`
+	UseSeverity bool   `yaml:"useSeverity"`
 }
 
 type SyslogSink struct {
-	sw *syslog.Writer
+	sw          *syslog.Writer
+	useSeverity bool
 }
 
 func NewSyslogSink(config *SyslogConfig) (Sink, error) {
@@ -22,23 +26,33 @@ func NewSyslogSink(config *SyslogConfig) (Sink, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &SyslogSink{sw: w}, nil
+	return &SyslogSink{sw: w, useSeverity: config.UseSeverity}, nil
 }
 
 func (w *SyslogSink) Close() {
 	w.sw.Close()
 }
 
+// Send writes the event, with the syslog severity of its severity if useSeverity is set
 func (w *SyslogSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
 
-	if b, err := json.Marshal(ev); err == nil {
-		_, writeErr := w.sw.Write(b)
-
-		if writeErr != nil {
-			return writeErr
-		}
-	} else {
+	if !w.useSeverity {
+		_, err := w.sw.Write(b)
 		return err
 	}
-	return nil
+
+	switch ev.GetSeverity() {
+	case kube.SeverityCritical:
+		return w.sw.Crit(string(b))
+	case kube.SeverityError:
+		return w.sw.Err(string(b))
+	case kube.SeverityWarning:
+		return w.sw.Warning(string(b))
+	default:
+		return w.sw.Info(string(b))
+	}
 }
@@ -12,9 +12,10 @@ import (
 )
 
 type TeamsConfig struct {
-	Endpoint string                 `yaml:"endpoint"`
-	Layout   map[string]interface{} `yaml:"layout"`
-	Headers  map[string]string      `yaml:"headers"`
+	Endpoint  string                 `yaml:"endpoint"`
+	Layout    map[string]interface{} `yaml:"layout"`
+	LayoutRef string                 `yaml:"layoutRef"`
+	Headers   map[string]string      `yaml:"headers"`
 }
 
 func NewTeamsSink(cfg *TeamsConfig) (Sink, error) {
@@ -3,27 +3,408 @@ package sinks
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+
 	"github.com/Masterminds/sprig"
 	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
-	"text/template"
 )
 
+// templateCache keeps the compiled templates by their text so that every template is parsed only once, when the
+// config is validated. Validation also records the paths every text has in the config, e.g.
+// "alerts.slack.fields.namespace", so that execution errors tell all the places the template comes from. Every
+// template is compiled on top of base, which holds the named templates of the config. The HTML templates are kept
+// apart, they are compiled with the named templates too.
+var templateCache = struct {
+	sync.RWMutex
+	missingKey string
+	consoleURL string
+	named      map[string]string
+	base       *template.Template
+	m          map[string]*template.Template
+	html       map[string]*htmltemplate.Template
+	paths      map[string][]string
+}{
+	missingKey: "default",
+	m:          make(map[string]*template.Template),
+	html:       make(map[string]*htmltemplate.Template),
+	paths:      make(map[string][]string),
+}
+
+// SetTemplateMissingKey sets the missingkey option of the templates, "default", "zero" or "error". With "error",
+// referring to a missing map key, e.g. a label the object does not have, fails the template.
+func SetTemplateMissingKey(missingKey string) error {
+	switch missingKey {
+	case "":
+		missingKey = "default"
+	case "default", "invalid", "zero", "error":
+	default:
+		return fmt.Errorf("invalid missingkey option %q", missingKey)
+	}
+
+	templateCache.Lock()
+	defer templateCache.Unlock()
+	if templateCache.missingKey == missingKey {
+		return nil
+	}
+
+	base, err := newBaseTemplate(missingKey, templateCache.named)
+	if err != nil {
+		return err
+	}
+	templateCache.missingKey = missingKey
+	templateCache.base = base
+	templateCache.m = make(map[string]*template.Template)
+	templateCache.html = make(map[string]*htmltemplate.Template)
+	return nil
+}
+
+// SetNamedTemplates defines the templates that can be used in every other template with
+// {{ template "name" . }}. The files matching the globs are parsed too, they can define several templates
+// with {{ define "name" }}.
+func SetNamedTemplates(templates map[string]string, globs []string) error {
+	named := make(map[string]string, len(templates))
+	for name, text := range templates {
+		named[name] = text
+	}
+
+	for _, glob := range globs {
+		files, err := filepath.Glob(glob)
+		if err != nil {
+			return fmt.Errorf("template files %s: %w", glob, err)
+		}
+		for _, file := range files {
+			b, err := ioutil.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			named[file] = string(b)
+		}
+	}
+
+	templateCache.Lock()
+	defer templateCache.Unlock()
+	base, err := newBaseTemplate(templateCache.missingKey, named)
+	if err != nil {
+		return err
+	}
+	templateCache.named = named
+	templateCache.base = base
+	templateCache.m = make(map[string]*template.Template)
+	templateCache.html = make(map[string]*htmltemplate.Template)
+	return nil
+}
+
+func newBaseTemplate(missingKey string, named map[string]string) (*template.Template, error) {
+	base := template.New("").
+		Funcs(templateFuncs()).
+		Option("missingkey=" + missingKey)
+
+	for _, name := range namedTemplateNames(named) {
+		if _, err := base.New(name).Parse(named[name]); err != nil {
+			return nil, fmt.Errorf("template %s: %w", name, err)
+		}
+	}
+
+	for _, t := range base.Templates() {
+		if err := checkTemplateRefs(base, t.Tree.Root); err != nil {
+			return nil, fmt.Errorf("template %s: %w", t.Name(), err)
+		}
+	}
+	return base, nil
+}
+
+// namedTemplateNames returns the names of the named templates sorted, so that redefinitions are resolved the same
+// way on every start
+func namedTemplateNames(named map[string]string) []string {
+	names := make([]string, 0, len(named))
+	for name := range named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseHTMLTemplate returns the compiled HTML template of the text. The values are escaped for the context they are
+// used in, and toRaw writes plain JSON.
+func parseHTMLTemplate(text string) (*htmltemplate.Template, error) {
+	templateCache.RLock()
+	tmpl, ok := templateCache.html[text]
+	missingKey := templateCache.missingKey
+	named := templateCache.named
+	templateCache.RUnlock()
+	if ok {
+		return tmpl, nil
+	}
+
+	funcs := htmltemplate.FuncMap(templateFuncs())
+	funcs["toRaw"] = func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+	tmpl = htmltemplate.New("template").
+		Funcs(funcs).
+		Option("missingkey=" + missingKey)
+	for _, name := range namedTemplateNames(named) {
+		if _, err := tmpl.New(name).Parse(named[name]); err != nil {
+			return nil, fmt.Errorf("template %s: %w", name, err)
+		}
+	}
+	if _, err := tmpl.Parse(text); err != nil {
+		return nil, err
+	}
+
+	templateCache.Lock()
+	templateCache.html[text] = tmpl
+	templateCache.Unlock()
+	return tmpl, nil
+}
+
+// executeHTMLTemplate renders the HTML template of the text with the data
+func executeHTMLTemplate(data interface{}, text string) (string, error) {
+	tmpl, err := parseHTMLTemplate(text)
+	if err != nil {
+		return "", err
+	}
+
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, data); err != nil {
+		if paths := templatePaths(text); len(paths) > 0 {
+			return "", fmt.Errorf("template %s: %w", strings.Join(paths, ", "), err)
+		}
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// parseTemplate returns the compiled template of the text
+func parseTemplate(text string) (*template.Template, error) {
+	templateCache.RLock()
+	tmpl, ok := templateCache.m[text]
+	missingKey := templateCache.missingKey
+	base := templateCache.base
+	templateCache.RUnlock()
+	if ok {
+		return tmpl, nil
+	}
+
+	if base != nil {
+		clone, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		tmpl = clone.New("template")
+	} else {
+		tmpl = template.New("template").
+			Funcs(templateFuncs()).
+			Option("missingkey=" + missingKey)
+	}
+
+	tmpl, err := tmpl.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTemplateRefs(tmpl, tmpl.Tree.Root); err != nil {
+		return nil, err
+	}
+
+	templateCache.Lock()
+	templateCache.m[text] = tmpl
+	templateCache.Unlock()
+	return tmpl, nil
+}
+
+// addTemplatePath records that the template text is used at the path of the config
+func addTemplatePath(path, text string) {
+	templateCache.Lock()
+	defer templateCache.Unlock()
+	for _, p := range templateCache.paths[text] {
+		if p == path {
+			return
+		}
+	}
+	templateCache.paths[text] = append(templateCache.paths[text], path)
+}
+
+// templatePaths returns the paths of the config the template text is used at
+func templatePaths(text string) []string {
+	templateCache.RLock()
+	defer templateCache.RUnlock()
+	return templateCache.paths[text]
+}
+
+// checkTemplateRefs makes sure that the templates used with {{ template "name" }} exist, otherwise it would only
+// fail when an event is rendered
+func checkTemplateRefs(tmpl *template.Template, node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkTemplateRefs(tmpl, child); err != nil {
+				return err
+			}
+		}
+	case *parse.TemplateNode:
+		if tmpl.Lookup(n.Name) == nil {
+			return fmt.Errorf("template %q is not defined", n.Name)
+		}
+	case *parse.IfNode:
+		return checkBranchRefs(tmpl, &n.BranchNode)
+	case *parse.RangeNode:
+		return checkBranchRefs(tmpl, &n.BranchNode)
+	case *parse.WithNode:
+		return checkBranchRefs(tmpl, &n.BranchNode)
+	}
+	return nil
+}
+
+func checkBranchRefs(tmpl *template.Template, n *parse.BranchNode) error {
+	if err := checkTemplateRefs(tmpl, n.List); err != nil {
+		return err
+	}
+	return checkTemplateRefs(tmpl, n.ElseList)
+}
+
+// rawMarker prefixes the output of toRaw so that layouts can tell a typed value from a rendered string
+const rawMarker = "\x00raw\x00"
+
+func templateFuncs() template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+	for name, f := range kubeFuncs() {
+		funcs[name] = f
+	}
+	funcs["toRaw"] = toRaw
+	return funcs
+}
+
+// toRaw keeps the type of the value when it is the only output of a layout leaf, e.g. count: "{{ toRaw .Count }}"
+// becomes a number and labels: "{{ toRaw .InvolvedObject.Labels }}" an object instead of strings.
+func toRaw(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return rawMarker + string(b), nil
+}
+
 func GetString(event *kube.EnhancedEvent, text string) (string, error) {
-	tmpl, err := template.New("template").Funcs(sprig.TxtFuncMap()).Parse(text)
+	rendered, err := executeTemplate(event, text)
+	if err != nil {
+		return "", err
+	}
+
+	// Outside layouts there is no type to keep, the value is used as JSON text
+	return strings.ReplaceAll(rendered, rawMarker, ""), nil
+}
+
+func executeTemplate(event *kube.EnhancedEvent, text string) (string, error) {
+	tmpl, err := parseTemplate(text)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 
 	buf := new(bytes.Buffer)
 	// TODO: Should we send event directly or more events?
 	err = tmpl.Execute(buf, event)
 	if err != nil {
+		if paths := templatePaths(text); len(paths) > 0 {
+			return "", fmt.Errorf("template %s: %w", strings.Join(paths, ", "), err)
+		}
 		return "", err
 	}
 
 	return buf.String(), nil
 }
 
+// renderLayoutValue renders a layout leaf. If the output is a single toRaw value, it is decoded to keep its type.
+func renderLayoutValue(event *kube.EnhancedEvent, text string) (interface{}, error) {
+	rendered, err := executeTemplate(event, text)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := strings.TrimSpace(rendered)
+	if strings.HasPrefix(trimmed, rawMarker) && strings.Count(trimmed, rawMarker) == 1 {
+		dec := json.NewDecoder(strings.NewReader(strings.TrimPrefix(trimmed, rawMarker)))
+		dec.UseNumber()
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		return kube.NormalizeNumbers(value), nil
+	}
+	return strings.ReplaceAll(rendered, rawMarker, ""), nil
+}
+
+// validateTemplates compiles all the templates in a receiver config, which are the strings in it that contain an
+// action. The path of a template is built from the YAML field names and the map keys leading to it.
+func validateTemplates(path string, v reflect.Value, visited map[uintptr]bool) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || visited[v.Pointer()] {
+			return nil
+		}
+		visited[v.Pointer()] = true
+		return validateTemplates(path, v.Elem(), visited)
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return validateTemplates(path, v.Elem(), visited)
+	case reflect.String:
+		if !strings.Contains(v.String(), "{{") {
+			return nil
+		}
+		if _, err := parseTemplate(v.String()); err != nil {
+			return fmt.Errorf("template %s: %w", path, err)
+		}
+		addTemplatePath(path, v.String())
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			if err := validateTemplates(path+"."+yamlFieldName(field), v.Field(i), visited); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := validateTemplates(fmt.Sprintf("%s.%v", path, iter.Key()), iter.Value(), visited); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := validateTemplates(fmt.Sprintf("%s[%d]", path, i), v.Index(i), visited); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// yamlFieldName returns the name of the field in the config as the YAML decoder sees it
+func yamlFieldName(field reflect.StructField) string {
+	if tag := strings.Split(field.Tag.Get("yaml"), ",")[0]; tag != "" {
+		return tag
+	}
+	return strings.ToLower(field.Name)
+}
+
 func convertLayoutTemplate(layout map[string]interface{}, ev *kube.EnhancedEvent) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
 
@@ -40,7 +421,7 @@ func convertLayoutTemplate(layout map[string]interface{}, ev *kube.EnhancedEvent
 func convertTemplate(value interface{}, ev *kube.EnhancedEvent) (interface{}, error) {
 	switch v := value.(type) {
 	case string:
-		rendered, err := GetString(ev, v)
+		rendered, err := renderLayoutValue(ev, v)
 		if err != nil {
 			return nil, err
 		}
@@ -78,7 +459,8 @@ func convertTemplate(value interface{}, ev *kube.EnhancedEvent) (interface{}, er
 		}
 		return listConf, nil
 	}
-	return nil, nil
+	// Numbers, booleans and nulls are kept as they are written in the layout
+	return value, nil
 }
 
 func serializeEventWithLayout(layout map[string]interface{}, ev *kube.EnhancedEvent) ([]byte, error) {
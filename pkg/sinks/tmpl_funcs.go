@@ -0,0 +1,187 @@
+package sinks
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// kubeFuncs are the template functions for events, added on top of sprig
+func kubeFuncs() map[string]interface{} {
+	return map[string]interface{}{
+		"severity":         severity,
+		"objectRef":        objectRef,
+		"consoleURL":       consoleURL,
+		"age":              age,
+		"humanizeDuration": humanizeDuration,
+		"truncate":         truncate,
+		"toYaml":           toYaml,
+		"labelSelector":    labelSelector,
+		"markdownEscape":   markdownEscape,
+		"slackEscape":      slackEscape,
+	}
+}
+
+// severity returns the severity of the event, info, warning, error or critical
+func severity(ev *kube.EnhancedEvent) string {
+	return ev.GetSeverity()
+}
+
+// objectRef returns the involved object as kind/namespace/name, or kind/name for cluster scoped objects
+func objectRef(ev *kube.EnhancedEvent) string {
+	if ev.InvolvedObject.Namespace == "" {
+		return ev.InvolvedObject.Kind + "/" + ev.InvolvedObject.Name
+	}
+	return ev.InvolvedObject.Kind + "/" + ev.InvolvedObject.Namespace + "/" + ev.InvolvedObject.Name
+}
+
+// SetConsoleURL sets the URL used by the consoleURL function. The placeholders {cluster}, {namespace}, {kind}, {name}
+// and {uid} are replaced with the values of the involved object, {kind} is lower case.
+func SetConsoleURL(url string) {
+	templateCache.Lock()
+	defer templateCache.Unlock()
+	templateCache.consoleURL = url
+}
+
+func consoleURL(ev *kube.EnhancedEvent) (string, error) {
+	templateCache.RLock()
+	url := templateCache.consoleURL
+	templateCache.RUnlock()
+	if url == "" {
+		return "", fmt.Errorf("consoleURL is not configured")
+	}
+
+	return strings.NewReplacer(
+		"{cluster}", ev.ClusterName,
+		"{namespace}", ev.InvolvedObject.Namespace,
+		"{kind}", strings.ToLower(ev.InvolvedObject.Kind),
+		"{name}", ev.InvolvedObject.Name,
+		"{uid}", string(ev.InvolvedObject.UID),
+	).Replace(url), nil
+}
+
+// age returns the time passed since the event happened, or since the given time
+func age(v interface{}) (time.Duration, error) {
+	var t time.Time
+	switch val := v.(type) {
+	case *kube.EnhancedEvent:
+		t = val.FirstTimestamp.Time
+		if t.IsZero() {
+			t = val.EventTime.Time
+		}
+	case metav1.Time:
+		t = val.Time
+	case metav1.MicroTime:
+		t = val.Time
+	case time.Time:
+		t = val
+	default:
+		return 0, fmt.Errorf("age: unsupported type %T", v)
+	}
+	return time.Since(t), nil
+}
+
+// humanizeDuration formats a duration, or a number of seconds, with its two largest units, e.g. 3d4h or 5m10s
+func humanizeDuration(v interface{}) (string, error) {
+	var d time.Duration
+	switch val := v.(type) {
+	case time.Duration:
+		d = val
+	case int:
+		d = time.Duration(val) * time.Second
+	case int32:
+		d = time.Duration(val) * time.Second
+	case int64:
+		d = time.Duration(val) * time.Second
+	case float64:
+		d = time.Duration(val * float64(time.Second))
+	default:
+		return "", fmt.Errorf("humanizeDuration: unsupported type %T", v)
+	}
+
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	if d < time.Second {
+		return sign + d.Round(time.Millisecond).String(), nil
+	}
+
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	}
+
+	// Only the two largest units are shown, the second one is left out if it is zero
+	for i, u := range units {
+		if d < u.size {
+			continue
+		}
+		out := fmt.Sprintf("%d%s", d/u.size, u.suffix)
+		if i+1 < len(units) {
+			if next := d % u.size / units[i+1].size; next > 0 {
+				out += fmt.Sprintf("%d%s", next, units[i+1].suffix)
+			}
+		}
+		return sign + out, nil
+	}
+	return sign + d.String(), nil
+}
+
+// truncate shortens the text to at most n bytes without splitting a character, for APIs with size limits
+func truncate(n int, s string) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// toYaml uses the JSON field names, so it prints the event like kubectl does
+func toYaml(v interface{}) (string, error) {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(b), "\n"), nil
+}
+
+// labelSelector formats labels as a selector, e.g. app=nginx,tier=web
+func labelSelector(m map[string]string) string {
+	return labels.SelectorFromSet(m).String()
+}
+
+var markdownEscaper = func() *strings.Replacer {
+	special := "\\`*_{}[]()#+-.!|<>~"
+	pairs := make([]string, 0, 2*len(special))
+	for _, c := range special {
+		pairs = append(pairs, string(c), "\\"+string(c))
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+// markdownEscape escapes the characters that have a meaning in Markdown
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// slackEscape escapes the control characters of Slack messages
+func slackEscape(s string) string {
+	return slackEscaper.Replace(s)
+}
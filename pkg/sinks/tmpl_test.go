@@ -1,6 +1,9 @@
 package sinks
 
 import (
+	"io/ioutil"
+	"path/filepath"
+
 	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -50,3 +53,256 @@ func TestLayoutConvert(t *testing.T) {
 
 	require.Equal(t, val2, ev.Message)
 }
+
+func TestGetStringParseError(t *testing.T) {
+	_, err := GetString(&kube.EnhancedEvent{}, "{{ .Message ")
+	require.Error(t, err)
+}
+
+func TestGetStringMissingKey(t *testing.T) {
+	ev := &kube.EnhancedEvent{}
+	ev.InvolvedObject.Labels = map[string]string{"app": "nginx"}
+
+	res, err := GetString(ev, "{{ .InvolvedObject.Labels.tier }}")
+	require.NoError(t, err)
+	require.Equal(t, "<no value>", res)
+
+	require.NoError(t, SetTemplateMissingKey("error"))
+	defer SetTemplateMissingKey("")
+
+	_, err = GetString(ev, "{{ .InvolvedObject.Labels.tier }}")
+	require.Error(t, err)
+
+	require.Error(t, SetTemplateMissingKey("ignore"))
+}
+
+func TestReceiverConfigValidateTemplates(t *testing.T) {
+	r := ReceiverConfig{
+		Name: "alerts",
+		Slack: &SlackConfig{
+			Message: "{{ .Message }}",
+			Fields: map[string]string{
+				"namespace": "{{ .Namespace",
+			},
+		},
+	}
+
+	err := r.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "template alerts.slack.fields.namespace")
+
+	r.Slack.Fields["namespace"] = "{{ .Namespace }}"
+	require.NoError(t, r.Validate())
+
+	r.Webhook = &WebhookConfig{
+		Layout: map[string]interface{}{
+			"details": map[interface{}]interface{}{
+				"tags": []interface{}{"{{ .Reason }", "static"},
+			},
+		},
+	}
+	err = r.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "template alerts.webhook.layout.details.tags[0]")
+}
+
+func TestTemplateExecErrorHasPath(t *testing.T) {
+	r := ReceiverConfig{
+		Name: "hook",
+		Webhook: &WebhookConfig{
+			Headers: map[string]string{"X-Count": "{{ .Count.Missing }}"},
+		},
+	}
+	require.NoError(t, r.Validate())
+
+	_, err := GetString(&kube.EnhancedEvent{}, "{{ .Count.Missing }}")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "hook.webhook.headers.X-Count")
+}
+
+func TestTemplateExecErrorHasAllPaths(t *testing.T) {
+	first := ReceiverConfig{
+		Name:    "first",
+		Webhook: &WebhookConfig{Headers: map[string]string{"X-Kind": "{{ .Kind.Missing }}"}},
+	}
+	second := ReceiverConfig{
+		Name:  "second",
+		Slack: &SlackConfig{Message: "{{ .Kind.Missing }}"},
+	}
+	require.NoError(t, first.Validate())
+	require.NoError(t, second.Validate())
+
+	// The execution error does not name the first receiver only
+	_, err := GetString(&kube.EnhancedEvent{}, "{{ .Kind.Missing }}")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "first.webhook.headers.X-Kind")
+	require.Contains(t, err.Error(), "second.slack.message")
+
+}
+
+func TestTemplateCompiledOnce(t *testing.T) {
+	r := ReceiverConfig{
+		Name:  "compiled",
+		Slack: &SlackConfig{Message: "{{ .Reason }} compiled once"},
+	}
+	require.NoError(t, r.Validate())
+
+	// The event is rendered with the template compiled by the validation
+	templateCache.RLock()
+	compiled := templateCache.m["{{ .Reason }} compiled once"]
+	size := len(templateCache.m)
+	templateCache.RUnlock()
+	require.NotNil(t, compiled)
+
+	_, err := GetString(&kube.EnhancedEvent{}, "{{ .Reason }} compiled once")
+	require.NoError(t, err)
+	tmpl, err := parseTemplate("{{ .Reason }} compiled once")
+	require.NoError(t, err)
+	require.Same(t, compiled, tmpl)
+	templateCache.RLock()
+	require.Equal(t, size, len(templateCache.m))
+	templateCache.RUnlock()
+}
+
+func TestLayoutTypedValues(t *testing.T) {
+	ev := &kube.EnhancedEvent{}
+	ev.Count = 5
+	ev.Message = "Back-off restarting failed container"
+	ev.InvolvedObject.Labels = map[string]string{"app": "nginx"}
+
+	layout := map[string]interface{}{
+		"count":     "{{ toRaw .Count }}",
+		"countPipe": "{{ .Count | toRaw }}",
+		"countText": "{{ .Count }}",
+		"labels":    "{{ toRaw .InvolvedObject.Labels }}",
+		"tags":      "{{ toRaw (list .Message \"k8s\") }}",
+		"mixed":     "count={{ toRaw .Count }}",
+		"enabled":   true,
+		"priority":  3,
+		"ratio":     0.5,
+		"nothing":   nil,
+	}
+
+	res, err := convertLayoutTemplate(layout, ev)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), res["count"])
+	require.Equal(t, int64(5), res["countPipe"])
+	require.Equal(t, "5", res["countText"])
+	require.Equal(t, map[string]interface{}{"app": "nginx"}, res["labels"])
+	require.Equal(t, []interface{}{ev.Message, "k8s"}, res["tags"])
+	require.Equal(t, "count=5", res["mixed"])
+	require.Equal(t, true, res["enabled"])
+	require.Equal(t, 3, res["priority"])
+	require.Equal(t, 0.5, res["ratio"])
+	require.Nil(t, res["nothing"])
+
+	b, err := serializeEventWithLayout(layout, ev)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"count":5`)
+	require.Contains(t, string(b), `"labels":{"app":"nginx"}`)
+}
+
+func TestGetStringRawIsText(t *testing.T) {
+	ev := &kube.EnhancedEvent{}
+	ev.Count = 5
+
+	res, err := GetString(ev, "count is {{ toRaw .Count }}")
+	require.NoError(t, err)
+	require.Equal(t, "count is 5", res)
+}
+
+func TestKubeTemplateFuncs(t *testing.T) {
+	ev := &kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.Reason = "OOMKilling"
+	ev.ClusterName = "prod"
+	ev.InvolvedObject.Kind = "Pod"
+	ev.InvolvedObject.Namespace = "default"
+	ev.InvolvedObject.Name = "nginx"
+	ev.InvolvedObject.Labels = map[string]string{"tier": "web", "app": "nginx"}
+	ev.Message = "a <b> & *c*"
+	ev.FirstTimestamp = v1.Time{Time: time.Now().Add(-3 * time.Hour)}
+
+	SetConsoleURL("https://console.example.com/{cluster}/ns/{namespace}/{kind}s/{name}")
+	defer SetConsoleURL("")
+
+	cases := map[string]string{
+		"{{ severity . }}":                           "critical",
+		"{{ objectRef . }}":                          "Pod/default/nginx",
+		"{{ consoleURL . }}":                         "https://console.example.com/prod/ns/default/pods/nginx",
+		"{{ age . | humanizeDuration }}":             "3h",
+		"{{ humanizeDuration 93784 }}":               "1d2h",
+		"{{ humanizeDuration 3605 }}":                "1h",
+		"{{ humanizeDuration 65 }}":                  "1m5s",
+		"{{ truncate 4 \"héllo\" }}":                 "hél",
+		"{{ truncate 3 \"héllo\" }}":                 "hé",
+		"{{ labelSelector .InvolvedObject.Labels }}": "app=nginx,tier=web",
+		"{{ slackEscape .Message }}":                 "a &lt;b&gt; &amp; *c*",
+		"{{ markdownEscape .Message }}":              "a \\<b\\> & \\*c\\*",
+		"{{ toYaml .InvolvedObject.Labels }}":        "app: nginx\ntier: web",
+	}
+
+	for tmpl, expected := range cases {
+		res, err := GetString(ev, tmpl)
+		require.NoError(t, err, tmpl)
+		require.Equal(t, expected, res, tmpl)
+	}
+
+	ev.Reason = "Unhealthy"
+	res, err := GetString(ev, "{{ severity . }}")
+	require.NoError(t, err)
+	require.Equal(t, "warning", res)
+}
+
+func TestNamedTemplates(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "slack.tmpl")
+	require.NoError(t, ioutil.WriteFile(file, []byte(`{{ define "slack.text" }}{{ template "slack.title" . }}: {{ .Message }}{{ end }}`), 0644))
+
+	err := SetNamedTemplates(map[string]string{
+		"slack.title": "[{{ .Type }}] {{ .InvolvedObject.Name }}",
+	}, []string{filepath.Join(dir, "*.tmpl")})
+	require.NoError(t, err)
+	defer SetNamedTemplates(nil, nil)
+
+	ev := &kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.InvolvedObject.Name = "nginx"
+	ev.Message = "Back-off restarting failed container"
+
+	res, err := GetString(ev, `{{ template "slack.text" . }}`)
+	require.NoError(t, err)
+	require.Equal(t, "[Warning] nginx: Back-off restarting failed container", res)
+
+	layout, err := convertLayoutTemplate(map[string]interface{}{"title": `{{ template "slack.title" . }}`}, ev)
+	require.NoError(t, err)
+	require.Equal(t, "[Warning] nginx", layout["title"])
+
+	// Unknown templates fail when the config is loaded, not when an event is sent
+	_, err = GetString(ev, `{{ if .Message }}{{ template "slack.missing" . }}{{ end }}`)
+	require.ErrorContains(t, err, `template "slack.missing" is not defined`)
+
+	err = SetNamedTemplates(map[string]string{"broken": `{{ template "nope" . }}`}, nil)
+	require.ErrorContains(t, err, `template "nope" is not defined`)
+	err = SetNamedTemplates(map[string]string{"broken": `{{ .Message`}, nil)
+	require.ErrorContains(t, err, "template broken")
+}
+
+func TestHTMLTemplateEscapes(t *testing.T) {
+	ev := &kube.EnhancedEvent{}
+	ev.Message = `<script>alert("x")</script>`
+	ev.InvolvedObject.Labels = map[string]string{"app": "<nginx>"}
+
+	html, err := executeHTMLTemplate(ev, `<p>{{ .Message }}</p>`)
+	require.NoError(t, err)
+	require.Equal(t, `<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;</p>`, html)
+
+	// The html function of the text templates does not escape twice
+	html, err = executeHTMLTemplate(ev, `<p>{{ .Message | html }}</p>`)
+	require.NoError(t, err)
+	require.Equal(t, `<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;</p>`, html)
+
+	html, err = executeHTMLTemplate(ev, `<pre>{{ toRaw .InvolvedObject.Labels }}</pre>`)
+	require.NoError(t, err)
+	require.Equal(t, `<pre>{&#34;app&#34;:&#34;\u003cnginx\u003e&#34;}</pre>`, html)
+}
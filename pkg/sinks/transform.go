@@ -0,0 +1,227 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+)
+
+// TransformConfig is one step of a transformation pipeline, it must have exactly one operation. The fields are
+// paths in the JSON view of the event such as reason, involvedObject.labels.app or metadata.annotations, a dot in
+// a key is escaped with a backslash. New fields at the top level are added to the output of the event.
+type TransformConfig struct {
+	// Set sets the fields to the values, string values are templates and can be static values too
+	Set map[string]interface{} `yaml:"set"`
+	// Copy copies the fields to other fields, e.g. a label of the object to a top level field
+	Copy map[string]string `yaml:"copy"`
+	// Rename moves the fields to other fields
+	Rename map[string]string `yaml:"rename"`
+	// Delete removes the fields
+	Delete []string `yaml:"delete"`
+	// Truncate shortens the string fields to a number of bytes
+	Truncate map[string]int `yaml:"truncate"`
+	// DeDot replaces the dots in the keys of the labels and annotations, see kube.EnhancedEvent.DeDot
+	DeDot bool `yaml:"deDot"`
+}
+
+// Validate checks that the transform has one operation and compiles its templates
+func (t *TransformConfig) Validate() error {
+	ops := 0
+	for _, set := range []bool{len(t.Set) > 0, len(t.Copy) > 0, len(t.Rename) > 0, len(t.Delete) > 0,
+		len(t.Truncate) > 0, t.DeDot} {
+		if set {
+			ops++
+		}
+	}
+	if ops != 1 {
+		return errors.New("transform must have exactly one of set, copy, rename, delete, truncate or deDot")
+	}
+
+	for field, n := range t.Truncate {
+		if n <= 0 {
+			return fmt.Errorf("truncate %s: length must be positive", field)
+		}
+	}
+	return validateTemplates("set", reflect.ValueOf(t.Set), make(map[uintptr]bool))
+}
+
+// ValidateTransforms validates the steps of a pipeline
+func ValidateTransforms(transforms []TransformConfig) error {
+	for i := range transforms {
+		if err := transforms[i].Validate(); err != nil {
+			return fmt.Errorf("transform %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// ApplyTransforms runs the event through the pipeline, the given event is not modified
+func ApplyTransforms(ev *kube.EnhancedEvent, transforms []TransformConfig) (*kube.EnhancedEvent, error) {
+	for i := range transforms {
+		var err error
+		if ev, err = transforms[i].apply(ev); err != nil {
+			return nil, fmt.Errorf("transform %d: %w", i, err)
+		}
+	}
+	return ev, nil
+}
+
+func (t *TransformConfig) apply(ev *kube.EnhancedEvent) (*kube.EnhancedEvent, error) {
+	if t.DeDot {
+		dedotted := ev.DeDot()
+		return &dedotted, nil
+	}
+
+	m, err := ev.ToMap()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(t.Set) > 0:
+		for _, field := range sortedKeys(t.Set) {
+			// Templates see the event as it was before this step
+			value, err := convertTemplate(t.Set[field], ev)
+			if err != nil {
+				return nil, err
+			}
+			if err := setField(m, splitFieldPath(field), value); err != nil {
+				return nil, err
+			}
+		}
+	case len(t.Copy) > 0:
+		for _, from := range sortedKeys(t.Copy) {
+			if value, ok := getField(m, splitFieldPath(from)); ok {
+				if err := setField(m, splitFieldPath(t.Copy[from]), value); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case len(t.Rename) > 0:
+		for _, from := range sortedKeys(t.Rename) {
+			if value, ok := getField(m, splitFieldPath(from)); ok {
+				deleteField(m, splitFieldPath(from))
+				if err := setField(m, splitFieldPath(t.Rename[from]), value); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case len(t.Delete) > 0:
+		for _, field := range t.Delete {
+			deleteField(m, splitFieldPath(field))
+		}
+	case len(t.Truncate) > 0:
+		for _, field := range sortedKeys(t.Truncate) {
+			path := splitFieldPath(field)
+			value, ok := getField(m, path)
+			if !ok {
+				continue
+			}
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("cannot truncate %s, it is a %T", field, value)
+			}
+			if err := setField(m, path, truncate(t.Truncate[field], s)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return kube.EventFromMap(m)
+}
+
+// sortedKeys returns the keys of a map with string keys in order, so the operations of a step run in the same order
+func sortedKeys(m interface{}) []string {
+	keys := make([]string, 0)
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// splitFieldPath splits a path on the dots that are not escaped with a backslash
+func splitFieldPath(path string) []string {
+	parts := make([]string, 0)
+	var current strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && path[i+1] == '.':
+			current.WriteByte('.')
+			i++
+		case path[i] == '.':
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(path[i])
+		}
+	}
+	return append(parts, current.String())
+}
+
+func getField(m map[string]interface{}, path []string) (interface{}, bool) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		m = next
+	}
+	value, ok := m[path[len(path)-1]]
+	return value, ok
+}
+
+// setField creates the maps on the path that do not exist yet
+func setField(m map[string]interface{}, path []string, value interface{}) error {
+	for i, key := range path[:len(path)-1] {
+		switch next := m[key].(type) {
+		case map[string]interface{}:
+			m = next
+		case nil:
+			created := make(map[string]interface{})
+			m[key] = created
+			m = created
+		default:
+			return fmt.Errorf("cannot set %s, %s is a %T", strings.Join(path, "."), strings.Join(path[:i+1], "."), next)
+		}
+	}
+	m[path[len(path)-1]] = value
+	return nil
+}
+
+func deleteField(m map[string]interface{}, path []string) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		m = next
+	}
+	delete(m, path[len(path)-1])
+}
+
+// Transformer wraps a sink to run the events through a transformation pipeline before sending them
+type Transformer struct {
+	sink       Sink
+	transforms []TransformConfig
+}
+
+func NewTransformer(sink Sink, transforms []TransformConfig) *Transformer {
+	return &Transformer{sink: sink, transforms: transforms}
+}
+
+func (t *Transformer) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	transformed, err := ApplyTransforms(ev, t.transforms)
+	if err != nil {
+		return err
+	}
+	return t.sink.Send(ctx, transformed)
+}
+
+func (t *Transformer) Close() {
+	t.sink.Close()
+}
@@ -0,0 +1,101 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/resmoio/kubernetes-event-exporter/pkg/kube"
+	"github.com/stretchr/testify/require"
+)
+
+func newTransformEvent() *kube.EnhancedEvent {
+	ev := &kube.EnhancedEvent{}
+	ev.Namespace = "default"
+	ev.Reason = "BackOff"
+	ev.Count = 4
+	ev.Message = "Back-off restarting failed container in pod nginx"
+	ev.InvolvedObject.Kind = "Pod"
+	ev.InvolvedObject.Namespace = "default"
+	ev.InvolvedObject.Name = "nginx"
+	ev.InvolvedObject.Labels = map[string]string{"app.kubernetes.io/name": "nginx", "team": "web"}
+	return ev
+}
+
+func TestApplyTransforms(t *testing.T) {
+	transforms := []TransformConfig{
+		{Set: map[string]interface{}{"region": "eu-west-1", "object": "{{ objectRef . }}", "repeats": "{{ toRaw .Count }}"}},
+		{Copy: map[string]string{
+			"involvedObject.labels.team":                       "team",
+			"involvedObject.labels.app\\.kubernetes\\.io/name": "app",
+		}},
+		{Rename: map[string]string{"reason": "kubernetes.reason"}},
+		{Delete: []string{"involvedObject.labels"}},
+		{Truncate: map[string]int{"message": 8}},
+	}
+	require.NoError(t, ValidateTransforms(transforms))
+
+	ev := newTransformEvent()
+	res, err := ApplyTransforms(ev, transforms)
+	require.NoError(t, err)
+
+	require.Equal(t, "Back-off", res.Message)
+	require.Equal(t, "", res.Reason)
+	require.Nil(t, res.InvolvedObject.Labels)
+	require.Equal(t, "nginx", res.InvolvedObject.Name)
+	require.Equal(t, map[string]interface{}{
+		"region":     "eu-west-1",
+		"object":     "Pod/default/nginx",
+		"repeats":    int64(4),
+		"team":       "web",
+		"app":        "nginx",
+		"kubernetes": map[string]interface{}{"reason": "BackOff"},
+	}, res.Fields)
+
+	// The fields are part of the output
+	var out map[string]interface{}
+	require.NoError(t, json.Unmarshal(res.ToJSON(), &out))
+	require.Equal(t, "eu-west-1", out["region"])
+	require.Equal(t, "Pod", out["involvedObject"].(map[string]interface{})["kind"])
+
+	// The original event is left as it is
+	require.Equal(t, "BackOff", ev.Reason)
+	require.Len(t, ev.InvolvedObject.Labels, 2)
+	require.Nil(t, ev.Fields)
+}
+
+func TestApplyTransforms_DeDot(t *testing.T) {
+	res, err := ApplyTransforms(newTransformEvent(), []TransformConfig{{DeDot: true}})
+	require.NoError(t, err)
+	require.Equal(t, "nginx", res.InvolvedObject.Labels["app_kubernetes_io/name"])
+}
+
+func TestApplyTransforms_Errors(t *testing.T) {
+	_, err := ApplyTransforms(newTransformEvent(), []TransformConfig{{Truncate: map[string]int{"count": 2}}})
+	require.ErrorContains(t, err, "cannot truncate count")
+
+	_, err = ApplyTransforms(newTransformEvent(), []TransformConfig{{Set: map[string]interface{}{"message.text": "x"}}})
+	require.ErrorContains(t, err, "cannot set message.text")
+}
+
+func TestTransformConfig_Validate(t *testing.T) {
+	require.Error(t, (&TransformConfig{}).Validate())
+	require.Error(t, (&TransformConfig{DeDot: true, Delete: []string{"reason"}}).Validate())
+	require.Error(t, (&TransformConfig{Truncate: map[string]int{"message": 0}}).Validate())
+	require.Error(t, (&TransformConfig{Set: map[string]interface{}{"x": "{{ .Reason"}}).Validate())
+	require.NoError(t, (&TransformConfig{Set: map[string]interface{}{"x": "{{ .Reason }}"}}).Validate())
+}
+
+func TestTransformer(t *testing.T) {
+	inMemory := &InMemory{}
+	r := &ReceiverConfig{
+		Name:       "in-mem",
+		InMemory:   &InMemoryConfig{},
+		Transforms: []TransformConfig{{Set: map[string]interface{}{"environment": "prod"}}},
+	}
+	require.NoError(t, r.Validate())
+
+	sink := NewTransformer(inMemory, r.Transforms)
+	require.NoError(t, sink.Send(context.Background(), newTransformEvent()))
+	require.Equal(t, "prod", inMemory.Events[0].Fields["environment"])
+}
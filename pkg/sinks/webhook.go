@@ -14,10 +14,11 @@ import (
 )
 
 type WebhookConfig struct {
-	Endpoint string                 `yaml:"endpoint"`
-	TLS      TLS                    `yaml:"tls"`
-	Layout   map[string]interface{} `yaml:"layout"`
-	Headers  map[string]string      `yaml:"headers"`
+	Endpoint  string                 `yaml:"endpoint"`
+	TLS       TLS                    `yaml:"tls"`
+	Layout    map[string]interface{} `yaml:"layout"`
+	LayoutRef string                 `yaml:"layoutRef"`
+	Headers   map[string]string      `yaml:"headers"`
 }
 
 func NewWebhook(cfg *WebhookConfig) (Sink, error) {